@@ -12,32 +12,31 @@ import (
 // WriteSVGBody writes the entire content of a Canvas out to a stream in SVG format.
 // XX Produces a complete <g ...>...</g> expression -- rename accordingly?
 func (c *Canvas) WriteSVGBody(dst io.Writer, config *svg.Config) {
+	g := config.Geom()
 
 	internal.MustFPrintf(dst, "  <g id='%s'>\n", "lines")
 	for _, l := range c.lines() {
-		l.Draw(dst)
+		l.Draw(dst, g)
 	}
 
 	internal.MustFPrintf(dst, "  </g>\n  <g id='%s'>\n", "triangles")
 	for _, tI := range c.triangles() {
-		tI.Draw(dst)
+		tI.Draw(dst, g)
 	}
 
 	internal.MustFPrintf(dst, "  </g>\n  <g id='%s'>\n", "roundedCorners")
 	for _, rc := range c.roundedCorners() {
-		const cornerRadius = svg.CellHeight
-		drawArc(dst, rc, cornerRadius)
+		drawArc(dst, g, rc)
 	}
 
 	internal.MustFPrintf(dst, "  </g>\n  <g id='%s'>\n", "circles")
 	for _, ci := range c.circles() {
-		const circleRadius = 6
-		ci.Draw(dst, circleRadius)
+		ci.Draw(dst, g, circleRadius(g))
 	}
 
 	internal.MustFPrintf(dst, "  </g>\n  <g id='%s'>\n", "bridges")
 	for _, bI := range c.bridges() {
-		bI.Draw(dst)
+		bI.Draw(dst, g)
 	}
 
 	internal.MustFPrintf(dst, "  </g>\n  <g id='%s'>\n", "text")
@@ -103,14 +102,14 @@ func (c *Canvas) triangles() (triangles []svg.Drawable) {
 		switch o {
 		case svg.O_N:
 			r := c.RuneAt(start.North())
-			if r == '-' || isJoint(r) && !isDot(r) {
+			if r == '-' || c.isJoint(r) && !isDot(r) {
 				needsNudging = true
 				triangles = append(triangles, newHalfStep(start, svg.O_N))
 			}
 		case svg.O_NW:
 			r := c.RuneAt(start.NWest())
 			// Need to draw a tail.
-			if r == '-' || isJoint(r) && !isDot(r) {
+			if r == '-' || c.isJoint(r) && !isDot(r) {
 				needsNudging = true
 				triangles = append(
 					triangles,
@@ -123,7 +122,7 @@ func (c *Canvas) triangles() (triangles []svg.Drawable) {
 			}
 		case svg.O_NE:
 			r := c.RuneAt(start.NEast())
-			if r == '-' || isJoint(r) && !isDot(r) {
+			if r == '-' || c.isJoint(r) && !isDot(r) {
 				needsNudging = true
 				triangles = append(
 					triangles,
@@ -136,13 +135,13 @@ func (c *Canvas) triangles() (triangles []svg.Drawable) {
 			}
 		case svg.O_S:
 			r := c.RuneAt(start.South())
-			if r == '-' || isJoint(r) && !isDot(r) {
+			if r == '-' || c.isJoint(r) && !isDot(r) {
 				needsNudging = true
 				triangles = append(triangles, newHalfStep(start, svg.O_S))
 			}
 		case svg.O_SE:
 			r := c.RuneAt(start.SEast())
-			if r == '-' || isJoint(r) && !isDot(r) {
+			if r == '-' || c.isJoint(r) && !isDot(r) {
 				needsNudging = true
 				triangles = append(
 					triangles,
@@ -155,7 +154,7 @@ func (c *Canvas) triangles() (triangles []svg.Drawable) {
 			}
 		case svg.O_SW:
 			r := c.RuneAt(start.SWest())
-			if r == '-' || isJoint(r) && !isDot(r) {
+			if r == '-' || c.isJoint(r) && !isDot(r) {
 				needsNudging = true
 				triangles = append(
 					triangles,
@@ -223,7 +222,7 @@ func (c *Canvas) roundedCorners() (corners []svg.RoundedCorner) {
 func (c *Canvas) isroundedCorner(i svg.XyIndex) svg.Orientation {
 	r := c.RuneAt(i)
 
-	if !isJoint(r) {
+	if !c.isJoint(r) {
 		return svg.O_NONE
 	}
 
@@ -42,11 +42,6 @@ type line struct {
 	State lineState  // Value is Unstarted or Started
 }
 
-const (
-	W = svg.CellWidth
-	H = svg.CellHeight
-)
-
 type lineState int
 
 const (
@@ -91,7 +86,7 @@ func (l *line) diagonal() bool {
 
 
 // lines returns a slice of all line Drawables,
-// in all possible directional orientations, that it can recognize in Canvas.Data[].
+// in all possible directional orientations, that it can recognize among the non-text cells of Canvas.
 func (c *Canvas) lines() (lines []line) {
 	horizontalMidlines := c.getlinesForSegment('-')
 	diagUplines := c.getlinesForSegment('/')
@@ -271,7 +266,7 @@ func (c *Canvas) HalfSteps() (lines []line) {
 func (c *Canvas) getlinesForSegment(segment rune) []line {
 	var iter svg.CanvasIterator
 	var orientation svg.Orientation
-	passThroughs := goat.CopySet(jointRunes)
+	passThroughs := goat.CopySet(c.joints)
 
 	switch segment {
 	case '-':
@@ -412,9 +407,10 @@ func (c *Canvas) getlines(
 
 
 // Draw a straight line as an SVG path.
-func (l line) Draw(out io.Writer) {
-	start := l.Start.AsPixel()
-	stop := l.Stop.AsPixel()
+func (l line) Draw(out io.Writer, g *svg.Geometry) {
+	W, H := g.CellWidth, g.CellHeight
+	start := l.Start.AsPixel(g)
+	stop := l.Stop.AsPixel(g)
 
 	// For cases when a vertical line hits a perpendicular like this:
 	//
@@ -493,10 +489,11 @@ func (l line) Draw(out io.Writer) {
 
 	// If either end is a hollow circle, back off drawing to the edge of the circle,
 	// rather extending as usual to center of the cell.
-	const (
-		ORTHO = 6
-		DIAG_X = 3  // XX  By eye, '3' is a bit too much'; '2' is not enough.
-		DIAG_Y = 5
+	radius := circleRadius(g)
+	var (
+		ORTHO = radius
+		DIAG_X = radius/2  // XX  By eye, '3' (of default radius 6) is a bit too much'; '2' is not enough.
+		DIAG_Y = radius*5/6
 	)
 	if (l.startRune == 'o') {  // XX  ? Easily generalized to needs of BOX chars?   
 		switch l.Orientation {
@@ -531,5 +528,5 @@ func (l line) Draw(out io.Writer) {
 			panic("impossible orientation")
 		}
 	}
-	svg.WritePolyline(out, start, stop)
+	svg.WritePolyline(out, start, stop, svg.SourceLine(l.Start, l.Stop))
 }
@@ -0,0 +1,143 @@
+package ascii
+
+import (
+	"unicode"
+
+	"github.com/blampe/goat/svg"
+)
+
+// Lint reports constructs likely to be drawn otherwise than intended: lines ending one
+// space short of a joint, '+' joints meeting no line, arrowheads attached to no line,
+// and letters drawn as graphics though adjoining other letters or digits; and as notes,
+// '+' joints with a single arm.
+//
+// X  User-defined joints are seen by RuneAt() as '+', but reported as written.
+func (c *Canvas) Lint(config *svg.Config) {
+	warn := func(i svg.XyIndex, code, format string, args ...any) {
+		c.Diagnose(config, i, svg.SeverityWarning, code, format, args...)
+	}
+	gap := func(i svg.XyIndex) bool {
+		r, found := c.DataRuneAt(i)
+		return found && r == ' '
+	}
+	// Whether a line ends at 'near', short of a '+' beyond.
+	dangles := func(near, beyond svg.XyIndex) bool {
+		r, found := c.DataRuneAt(beyond)
+		return gap(near) && found && r == '+'
+	}
+
+	for idx := range svg.LeftRightMinor(c.Width, c.Height) {
+		r, found := c.DataRuneAt(idx)
+		if !found {
+			continue
+		}
+		switch r {
+		case '-':
+			e, w := idx.East(), idx.West()
+			if dangles(e, e.East()) {
+				warn(e, svg.CodeDanglingLine, "horizontal line ends one space short of a joint")
+			}
+			if dangles(w, w.West()) {
+				warn(w, svg.CodeDanglingLine, "horizontal line ends one space short of a joint")
+			}
+		case '|':
+			s, n := idx.South(), idx.North()
+			if dangles(s, s.South()) {
+				warn(s, svg.CodeDanglingLine, "vertical line ends one space short of a joint")
+			}
+			if dangles(n, n.North()) {
+				warn(n, svg.CodeDanglingLine, "vertical line ends one space short of a joint")
+			}
+		case '+':
+			// X  A single arm is common and intended -- "+-->", corners of open
+			//    shapes -- so merits only a note.
+			switch c.arms(idx) {
+			case 0:
+				warn(idx, svg.CodeLoneJoint,
+					"joint '%c' meets no line", c.WrittenRuneAt(idx))
+			case 1:
+				c.Diagnose(config, idx, svg.SeverityNote, svg.CodeOneArmedJoint,
+					"joint '%c' has a single arm, so is drawn as would be a line",
+					c.WrittenRuneAt(idx))
+			}
+		}
+		if unicode.IsLetter(r) {
+			for _, near := range []svg.XyIndex{idx.West(), idx.East()} {
+				n, ok := c.TextRuneAt(near)
+				if !ok {
+					n, ok = c.DataRuneAt(near)
+				}
+				if ok && (unicode.IsLetter(n) || unicode.IsDigit(n)) {
+					warn(idx, svg.CodeLetterAsGraphics,
+						"letter '%c' is drawn as graphics, though next to '%c'", r, n)
+					break
+				}
+			}
+		}
+	}
+
+	for _, d := range c.triangles() {
+		t, ok := d.(svg.Triangle)
+		if ok && !t.NeedsNudging && !c.arrowheadAttached(t) {
+			r, _ := c.DataRuneAt(t.Start)
+			warn(t.Start, svg.CodeLooseArrowhead,
+				"arrowhead '%c' is attached to no line", r)
+		}
+	}
+}
+
+// Counts the line segments meeting at the joint 'i'.
+func (c *Canvas) arms(i svg.XyIndex) (n int) {
+	is := func(near svg.XyIndex, runes ...rune) {
+		r := c.RuneAt(near)
+		for _, arm := range runes {
+			if r == arm {
+				n++
+				return
+			}
+		}
+		if c.isJoint(r) {
+			n++
+		}
+	}
+	is(i.North(), '|', '^')
+	is(i.South(), '|', 'v')
+	is(i.East(), '-', '>')
+	is(i.West(), '-', '<')
+	is(i.NEast(), '/')
+	is(i.SWest(), '/')
+	is(i.NWest(), '\\')
+	is(i.SEast(), '\\')
+	return
+}
+
+// Reports whether a line leads into the arrowhead 't' from behind its tip.
+func (c *Canvas) arrowheadAttached(t svg.Triangle) bool {
+	var behind svg.XyIndex
+	var tails []rune
+	switch t.Orientation {
+	case svg.O_E:
+		behind, tails = t.Start.West(), []rune{'-'}
+	case svg.O_W:
+		behind, tails = t.Start.East(), []rune{'-'}
+	case svg.O_N:
+		behind, tails = t.Start.South(), []rune{'|'}
+	case svg.O_S:
+		behind, tails = t.Start.North(), []rune{'|'}
+	case svg.O_NE:
+		behind, tails = t.Start.SWest(), []rune{'/'}
+	case svg.O_SW:
+		behind, tails = t.Start.NEast(), []rune{'/'}
+	case svg.O_NW:
+		behind, tails = t.Start.SEast(), []rune{'\\'}
+	case svg.O_SE:
+		behind, tails = t.Start.NWest(), []rune{'\\'}
+	}
+	r := c.RuneAt(behind)
+	for _, tail := range tails {
+		if r == tail {
+			return true
+		}
+	}
+	return c.isJoint(r)
+}
@@ -7,20 +7,30 @@ import (
 )
 
 // XX  ? Collect all ASCII-specific drawing functions into one file?
-func drawArc(out io.Writer, rc svg.RoundedCorner, radius int) {
-	startPixel := rc.Start.AsPixel()
+//
+// The arc spans from the center of the cell to one side of 'rc.Start', to the center of
+// the cell diagonally adjacent on the other side -- so for a 2:1 cell, a circle of
+// radius equal to the cell height.
+func drawArc(out io.Writer, g *svg.Geometry, rc svg.RoundedCorner) {
+	startPixel := rc.Start.AsPixel(g)
+	radius := svg.Pixel{X: 2*g.CellWidth, Y: g.CellHeight}
 	var delta svg.Pixel
 
 	switch rc.Orientation {
 	case svg.O_NW:
-		delta = svg.Pixel{ radius/2, radius}
+		delta = svg.Pixel{X: radius.X/2, Y: radius.Y}
 	case svg.O_SW:
-		delta = svg.Pixel{ radius/2,-radius}
+		delta = svg.Pixel{X: radius.X/2, Y: -radius.Y}
 	case svg.O_NE:
-		delta = svg.Pixel{-radius/2, radius}
+		delta = svg.Pixel{X: -radius.X/2, Y: radius.Y}
 	case svg.O_SE:
-		delta = svg.Pixel{-radius/2,-radius}
+		delta = svg.Pixel{X: -radius.X/2, Y: -radius.Y}
 	}
 	centerPixel := startPixel.Sum(delta)
 	rc.DrawCentered(out, centerPixel, radius)
 }
+
+// Default radius of an 'o' or '*' circle, which straddles the boundaries of its cell.
+func circleRadius(g *svg.Geometry) float64 {
+	return g.CircleRadiusOr(0.75)
+}
@@ -0,0 +1,101 @@
+package ascii
+
+import (
+	"unicode"
+
+	"github.com/blampe/goat"
+	"github.com/blampe/goat/svg"
+)
+
+// Classification of reserved runes by the whitespace-delimited token containing them,
+// for svg.ClassifierWords.
+//
+// X  Only tokens that look like words are decided here, e.g. " over ", "o<name>",
+//    "x-ray" or " v. ".  Others e.g. "o-->" or "+--." are left to the neighborhood
+//    rules of ShouldMoveToTextRunes().
+
+// Returns whether the reserved rune at 'i' is text, if 'decided'.
+func (c *Canvas) wordShouldMoveToText(i svg.XyIndex) (text, decided bool) {
+	if c.RuneAt(i) == ' ' {
+		return false, false
+	}
+	first, last := c.tokenAround(i)
+	if !c.looksLikeWord(i.Y, first, last) {
+		return false, false
+	}
+
+	// A line arriving from above or below attaches only at an end of the token,
+	// as in
+	//         |
+	//         +label
+	atEnd := i.X == first || i.X == last
+	if atEnd && c.haslineAboveOrBelow(i) {
+		return false, true
+	}
+	if c.attachedToHorizontalLine(i, first, last) {
+		return false, true
+	}
+	return true, true
+}
+
+// A token looks like a word if it contains a letter or digit not reserved, two
+// letters in succession, or a letter followed by punctuation ending the token, as
+// in "o," or "v.".
+func (c *Canvas) looksLikeWord(y, first, last int) bool {
+	at := func(x int) rune {
+		return c.RuneAt(svg.XyIndex{X: x, Y: y})
+	}
+	for x := first; x <= last; x++ {
+		r := at(x)
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if _, reserved := c.reserved[r]; !reserved {
+			return true
+		}
+		if x < last && unicode.IsLetter(r) && unicode.IsLetter(at(x+1)) {
+			return true
+		}
+		if x+1 == last && unicode.IsLetter(r) && sentencePunctuation.Contains(at(last)) {
+			return true
+		}
+	}
+	return false
+}
+
+var sentencePunctuation = goat.MakeRuneSet('.', ',', ';', ':', '!', '?')
+
+// Returns the columns of the first and last non-space runes of the token containing 'i'.
+func (c *Canvas) tokenAround(i svg.XyIndex) (first, last int) {
+	first, last = i.X, i.X
+	for c.RuneAt(svg.XyIndex{X: first - 1, Y: i.Y}) != ' ' {
+		first--
+	}
+	for c.RuneAt(svg.XyIndex{X: last + 1, Y: i.Y}) != ' ' {
+		last++
+	}
+	return
+}
+
+// Reports whether, moving left or right from 'i' within the token [first, last]
+// across reserved runes only, a horizontal line of at least two runes is reached,
+// as in "name-->" or "<--name".
+func (c *Canvas) attachedToHorizontalLine(i svg.XyIndex, first, last int) bool {
+	isLineRune := func(x int) bool {
+		r := c.RuneAt(svg.XyIndex{X: x, Y: i.Y})
+		return r == '-' || r == '_'
+	}
+	isReserved := func(x int) bool {
+		_, found := c.reserved[c.RuneAt(svg.XyIndex{X: x, Y: i.Y})]
+		return found
+	}
+	for _, step := range []int{-1, 1} {
+		for x := i.X; x >= first && x <= last && isReserved(x); x += step {
+			next := x + step
+			if next >= first && next <= last && isLineRune(x) && isLineRune(next) {
+				return true
+			}
+		}
+	}
+	return false
+}
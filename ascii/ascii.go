@@ -13,19 +13,36 @@ import (
 // X  Differs from utf8.Canvas by the methods bound to it (see below).
 type Canvas struct {
 	svg.CanvasCommon
+
+	classifier svg.Classifier
+
+	// ReservedSet, jointRunes and wideSVGSet, as adjusted by svg.Config.RuneRoles.
+	reserved, joints, wide goat.RuneSet
 }
 
 func (ac *Canvas) GetCommon() *svg.CanvasCommon {
 	return &ac.CanvasCommon
 }
 
+// Makes the dialect available to svg.Render() and cmd/goat as "ascii".
+func init() {
+	svg.RegisterDialect("ascii", ReservedSet, NewCanvas)
+}
+
 func NewCanvas(config *svg.Config, in io.Reader) svg.AbstractCanvas {
-	c := Canvas{
-		CanvasCommon: svg.NewCanvasCommon(config, in),
+	c := &Canvas{
+		classifier: config.Classifier,
+		reserved: config.ReservedSet(ReservedSet),
+		// X  User-defined joints are seen as '+', so need not be added.
+		joints: goat.CopySet(jointRunes),
+		wide: goat.CopySet(wideSVGSet),
 	}
-	// Fill the 'TextRunes' map, with runes removed from 'data', according to c.ShouldMoveToTextRunes()
-	svg.MoveToText(&c)
-	return &c
+	for r := range config.RuneRoles.TextRunes {
+		delete(c.joints, r)
+		delete(c.wide, r)
+	}
+	// User-defined joints are drawn as would be '+'.
+	return svg.ReadCanvas(config, in, c, '+')
 }
 
 var verticalRunes = goat.MakeRuneSet(
@@ -100,8 +117,8 @@ func isDot(r rune) bool {
 	return r == 'o' || r == '*'
 }
 
-func isJoint(r rune) bool {
-	return jointRunes.Contains(r)
+func (c *Canvas) isJoint(r rune) bool {
+	return c.joints.Contains(r)
 }
 
 func isTriangle(r rune) bool {
@@ -112,10 +129,16 @@ func (c *Canvas) ShouldMoveToTextRunes(i svg.XyIndex) bool {
 	i_r := c.RuneAt(i)
 	// character := string(i_r); _ = character   // for debug
 
-	if _, found := ReservedSet[i_r]; !found {
+	if _, found := c.reserved[i_r]; !found {
 		return true
 	}
 
+	if c.classifier == svg.ClassifierWords {
+		if text, decided := c.wordShouldMoveToText(i); decided {
+			return text
+		}
+	}
+
 	// X  After this point, deal with problematic cases of 'reserved' characters that
 	//    nevertheless must be treated as ordinary text.
 
@@ -125,16 +148,16 @@ func (c *Canvas) ShouldMoveToTextRunes(i svg.XyIndex) bool {
 		return false
 	}
 
-	// Returns true if index 'i' of c.Data[] is to be treated as reserved.
+	// Returns true if index 'i' is to be treated as reserved.
 	// X  Characters like 'o' and 'v' need more context (e.g., are other text characters
 	//    nearby) to determine whether they're part of a diagram.
 	isReserved := func(i svg.XyIndex) (found bool) {
-		i_r, inData := c.Data[i]
+		i_r, inData := c.DataRuneAt(i)
 		if !inData {
 			// lies off left or right end of line, treat as reserved
 			return true
 		}
-		_, found = ReservedSet[i_r]
+		_, found = c.reserved[i_r]
 		return
 	}
 
@@ -152,8 +175,8 @@ func (c *Canvas) ShouldMoveToTextRunes(i svg.XyIndex) bool {
 	}
 
 	crowded := func (l, r svg.XyIndex) bool {
-		return  svg.InSet(wideSVGSet, c.Data, l) &&
-			svg.InSet(wideSVGSet, c.Data, r)
+		return  c.DataInSet(c.wide, l) &&
+			c.DataInSet(c.wide, r)
 	}
 	if crowded(left, i) || crowded(i, right) {
 		return true
@@ -207,13 +230,13 @@ func (c *Canvas) partOfVerticalline(i svg.XyIndex) bool {
 	north := c.RuneAt(i.North())
 	south := c.RuneAt(i.South())
 
-	jointAboveMe := verticalRunes.Contains(this) && isJoint(north)
+	jointAboveMe := verticalRunes.Contains(this) && c.isJoint(north)
 
 	if verticalRunes.Contains(north) || jointAboveMe {
 		return true
 	}
 
-	jointBelowMe := verticalRunes.Contains(this) && isJoint(south)
+	jointBelowMe := verticalRunes.Contains(this) && c.isJoint(south)
 
 	if verticalRunes.Contains(south) || jointBelowMe {
 		return true
@@ -241,9 +264,9 @@ func (c *Canvas) partOfDiagonalline(i svg.XyIndex) bool {
 	switch r {
 	// Diagonal segments can be connected to joint or other segments.
 	case '/':
-		return ne == r || sw == r || isJoint(ne) || isJoint(sw) || n == '\\' || s == '\\'
+		return ne == r || sw == r || c.isJoint(ne) || c.isJoint(sw) || n == '\\' || s == '\\'
 	case '\\':
-		return nw == r || se == r || isJoint(nw) || isJoint(se) || n == '/' || s == '/'
+		return nw == r || se == r || c.isJoint(nw) || c.isJoint(se) || n == '/' || s == '/'
 
 	// For everything else just check if we have segments next to us.
 	default:
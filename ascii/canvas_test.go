@@ -2,6 +2,8 @@ package ascii
 
 import (
 	"bytes"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/blampe/goat/svg"
@@ -35,3 +37,188 @@ func TestReadASCII(t *testing.T) {
 	expected := buf.String()
 	AssertEqual(t, expected, svg.CanvasString(canvas))
 }
+
+func TestCropAndTrim(t *testing.T) {
+	var buf bytes.Buffer
+
+	buf.WriteString("         \n")
+	buf.WriteString(" over |  \n")
+	buf.WriteString("  +---+  \n")
+	buf.WriteString("         \n")
+
+	// The 'v' of "over" remains text, though its left neighbor is cropped away.
+	config := svg.Config{
+		Cols: svg.Span{First: 3},
+		Trim: true,
+	}
+	canvas := NewCanvas(&config, &buf)
+
+	AssertEqual(t, canvas.GetCommon().Width, 5)
+	AssertEqual(t, canvas.GetCommon().Height, 2)
+	r, _ := canvas.GetCommon().TextRuneAt(svg.XyIndex{X: 0, Y: 0})
+	AssertEqual(t, r, 'v')
+	AssertEqual(t, svg.CanvasString(canvas), "ver |\n+---+\n")
+}
+
+func TestSourceMap(t *testing.T) {
+	var buf bytes.Buffer
+
+	buf.WriteString("    \n")
+	buf.WriteString("  o--->\n")
+
+	config := svg.Config{
+		Cols: svg.Span{First: 2},
+		SourceMap: true,
+	}
+	canvas := NewCanvas(&config, &buf)
+	var out bytes.Buffer
+	svg.WriteCanvas(&config, canvas, false, "", nil, &out)
+	svgString := out.String()
+
+	// Positions are those of the input, before cropping.
+	for _, want := range []string{
+		`class="hollow" data-goat-row="2" data-goat-col="3"`,
+		`class="path" points="14,16 40,16" data-goat-row="2" data-goat-col="3" data-goat-end-row="2" data-goat-end-col="7"`,
+		`class="arrowhead" data-goat-row="2" data-goat-col="7"`,
+	} {
+		if !strings.Contains(svgString, want) {
+			t.Errorf("missing %s in:\n%s", want, svgString)
+		}
+	}
+
+	// Rows count the lines dropped by Config.LineFilter.
+	buf.Reset()
+	buf.WriteString("# dropped\n")
+	buf.WriteString("  o--->\n")
+	config = svg.Config{
+		LineFilter: regexp.MustCompile(`^[^#]`),
+		SourceMap: true,
+	}
+	canvas = NewCanvas(&config, &buf)
+	out.Reset()
+	svg.WriteCanvas(&config, canvas, false, "", nil, &out)
+	want := `class="hollow" data-goat-row="2" data-goat-col="3"`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("missing %s in:\n%s", want, out.String())
+	}
+}
+
+func TestWordsClassifier(t *testing.T) {
+	input := "" +
+		" over o<name> v. \n" +
+		" a--->b  name-->\n" +
+		"  |\n" +
+		"  +label\n"
+
+	textAt := func(classifier svg.Classifier, x, y int) bool {
+		config := svg.Config{Classifier: classifier}
+		canvas := NewCanvas(&config, bytes.NewBufferString(input))
+		_, found := canvas.GetCommon().TextRuneAt(svg.XyIndex{X: x, Y: y})
+		return found
+	}
+	for _, tc := range []struct {
+		x, y int
+		text bool
+	}{
+		{2, 0, true},   // 'v' of "over"
+		{6, 0, true},   // 'o' of "o<name>"
+		{7, 0, true},   // '<'
+		{14, 0, true},  // 'v' of "v."
+		{3, 1, false},  // '-' of "a--->b"
+		{5, 1, false},  // '>'
+		{14, 1, false}, // '-' of "name-->"
+		{2, 3, false},  // '+' of "+label", attached to '|' above
+	} {
+		AssertEqual(t, textAt(svg.ClassifierWords, tc.x, tc.y), tc.text)
+	}
+	// Unchanged by default.
+	AssertEqual(t, textAt(svg.ClassifierClassic, 6, 0), false)
+}
+
+func TestRender(t *testing.T) {
+	const diagram = " +-->\n | a\n +---+\n"
+
+	config, err := svg.NewConfig(ReservedSet, svg.MarkBindingMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	svg.WriteCanvas(&config, NewCanvas(&config, strings.NewReader(diagram)),
+		true, svg.ColorsOnlyCssFileContent("#000", "#FFF"), nil, &want)
+
+	var got bytes.Buffer
+	err = svg.Render(&got, strings.NewReader(diagram), svg.RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEqual(t, got.String(), want.String())
+
+	err = svg.Render(&got, strings.NewReader(diagram), svg.RenderOptions{Dialect: "no-such"})
+	if err == nil || !strings.Contains(err.Error(), "ascii") {
+		t.Errorf("expected error listing registered dialects, got %v", err)
+	}
+
+	// An ill-formed GoAT property is an error, not a panic.
+	err = svg.Render(&got, strings.NewReader(diagram), svg.RenderOptions{
+		CSS: []svg.CSSFile{{Name: "x.css", Content: []byte(`svg { goat-text-runes: ; }`)}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "goat-text-runes must have a single value") {
+		t.Errorf("expected error of goat-text-runes, got %v", err)
+	}
+}
+
+func TestLint(t *testing.T) {
+	input := "" +
+		" +-- +\n" +
+		"\n" +
+		" +-->\n" +
+		"\n" +
+		"  ^ \n" +
+		"\n" +
+		"  +\n"
+	config := svg.Config{}
+	canvas := NewCanvas(&config, strings.NewReader(input))
+	svg.Lint(&config, canvas, 5)
+
+	var got []string
+	for _, d := range config.Diagnostics {
+		got = append(got, d.Error())
+	}
+	AssertEqual(t, got, []string{
+		"1:6: warning: row is 6 characters wide, exceeding the limit of 5 [lint/row-too-wide]",
+		"5:4: warning: trailing whitespace [lint/trailing-whitespace]",
+		"1:2: note: joint '+' has a single arm, so is drawn as would be a line [lint/one-armed-joint]",
+		"1:5: warning: horizontal line ends one space short of a joint [lint/dangling-line]",
+		"1:6: warning: joint '+' meets no line [lint/lone-joint]",
+		"3:2: note: joint '+' has a single arm, so is drawn as would be a line [lint/one-armed-joint]",
+		"7:3: warning: joint '+' meets no line [lint/lone-joint]",
+		"5:3: warning: arrowhead '^' is attached to no line [lint/loose-arrowhead]",
+	})
+}
+
+func TestRuneRoles(t *testing.T) {
+	bindings := make(svg.MarkBindingMap)
+	err := svg.ParseCss(bindings, []byte(`svg { goat-text-runes: "o"; goat-joint-runes: "#"; }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := svg.NewConfig(ReservedSet, bindings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const input = "" +
+		"#--#  o\n" +
+		"|  |\n" +
+		"#--#\n"
+	canvas := NewCanvas(&config, strings.NewReader(input)).(*Canvas)
+
+	// Drawn as '+', but shown as written.
+	AssertEqual(t, canvas.RuneAt(svg.XyIndex{X: 0, Y: 0}), '+')
+	AssertEqual(t, canvas.WrittenRuneAt(svg.XyIndex{X: 0, Y: 0}), '#')
+	AssertEqual(t, svg.CanvasString(canvas), "#--#  o\n|  |   \n#--#   \n")
+
+	// No longer a joint, nor "wide", when text.
+	AssertEqual(t, canvas.isJoint('o'), false)
+	AssertEqual(t, canvas.wide.Contains('o'), false)
+	AssertEqual(t, canvas.isJoint('*'), true)
+}
@@ -6,7 +6,7 @@ import (
 
 	"github.com/blampe/goat/internal/testlib"
 
-	"github.com/blampe/goat/ascii"
+	_ "github.com/blampe/goat/ascii"
 )
 
 func TestMain(m *testing.M) {
@@ -18,5 +18,5 @@ func TestMain(m *testing.M) {
 }
 
 func TestExamples(t *testing.T) {
-	testlib.Regression(t, ascii.ReservedSet, ascii.NewCanvas)
+	testlib.RegressionDialect(t, "ascii")
 }
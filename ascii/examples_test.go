@@ -2,12 +2,10 @@ package ascii_test
 
 import (
 	"bytes"
-	"io"
 	"os"
 	"path/filepath"
 	"testing"
 
-	"github.com/blampe/goat/internal"
 	"github.com/blampe/goat/svg"
 	"github.com/blampe/goat/internal/testlib"
 
@@ -38,11 +36,13 @@ func TestExampleStableOutput(t *testing.T) {
 }
 
 func BenchmarkComplicated(b *testing.B) {
-	in := internal.MustOpen(filepath.FromSlash("examples/complicated.txt"))
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		ac := ascii.NewCanvas(&svg.Config{}, in)
-		testlib.WriteCanvasNoCssFiles( &svg.Config{}, ac, "", io.Discard)
+	src, err := os.ReadFile(filepath.FromSlash("examples/complicated.txt"))
+	if err != nil {
+		b.Fatal(err)
 	}
-	in.Close()
+	testlib.BenchmarkSource(b, src, ascii.NewCanvas)
+}
+
+func BenchmarkExamples(b *testing.B) {
+	testlib.BenchmarkExamples(b, ascii.NewCanvas)
 }
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/blampe/goat/internal"
+	"github.com/blampe/goat/svg"
+)
+
+// Code of Diagnostics for failure to write an output file.
+const codeWrite = "io/write"
+
+// A diagram file named as an argument, or found within a directory so named.
+type batchInput struct {
+	path string
+
+	// Path as mirrored under -outdir: that named, followed by the path relative to it
+	// of a file within a directory named.  See outRel().
+	rel string
+
+	// Problems preventing the file being drawn: it, or a glob pattern, matched nothing
+	// readable, or its SVG file would overwrite that of another.
+	diags []svg.Diagnostic
+}
+
+// Expand args.inputPaths to the diagram files named: glob patterns to their matches,
+// and directories to the files within them, recursively, whose names match an
+// -include pattern.  Whatever matches an -exclude pattern, by name or by path relative
+// to the directory named, is skipped.
+//
+// An argument that cannot be expanded is returned as an input carrying a Diagnostic,
+// in its place among the others, so that they may be drawn nonetheless.
+func expandInputs(args *Args) (inputs []batchInput) {
+	matchAny := func(patterns []string, names ...string) bool {
+		for _, p := range patterns {
+			for _, name := range names {
+				if ok, _ := path.Match(p, name); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	seen := make(map[string]bool)
+	add := func(in batchInput) {
+		if !seen[in.path] {
+			seen[in.path] = true
+			inputs = append(inputs, in)
+		}
+	}
+	failed := func(name string, err error) {
+		// X  The path is given by the Diagnostic's File.
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			err = pe.Err
+		}
+		d := svg.AsDiagnostic(err, svg.CodeIO)
+		d.File = name
+		inputs = append(inputs, batchInput{
+			path:  name,
+			rel:   outRel(name),
+			diags: []svg.Diagnostic{d},
+		})
+	}
+	for _, arg := range args.inputPaths {
+		matches := []string{arg}
+		if isGlob(arg) {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				failed(arg, err)
+				continue
+			}
+			if len(matches) == 0 {
+				failed(arg, errors.New("no files match"))
+				continue
+			}
+		}
+		for _, name := range matches {
+			info, err := os.Stat(name)
+			if err != nil {
+				failed(name, err)
+				continue
+			}
+			if !info.IsDir() {
+				// X  Named, or matched by a pattern given, so -include does not apply.
+				if matchAny(args.Exclude, filepath.Base(name)) {
+					continue
+				}
+				add(batchInput{path: name, rel: outRel(name)})
+				continue
+			}
+			_ = filepath.WalkDir(name, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					failed(p, err)
+					return nil
+				}
+				rel, _ := filepath.Rel(name, p)
+				if rel == "." {
+					return nil
+				}
+				if matchAny(args.Exclude, d.Name(), filepath.ToSlash(rel)) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !d.IsDir() && matchAny(args.Include, d.Name()) {
+					add(batchInput{path: p, rel: filepath.Join(outRel(name), rel)})
+				}
+				return nil
+			})
+		}
+	}
+	return
+}
+
+// The path mirrored under -outdir of the file or directory 'name' as named: itself if
+// relative and within the current directory, otherwise its base name.
+// X  So "-outdir out docs more" draws to out/docs/ and out/more/, but of "/tmp/docs"
+//    only "docs" is kept.
+func outRel(name string) string {
+	if filepath.IsLocal(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Base(name)
+}
+
+// Pathname of the SVG file drawn from 'in': beside it, or under -outdir.
+func (in batchInput) output(args *Args) string {
+	name := in.path
+	if len(args.OutDir) > 0 {
+		name = filepath.Join(args.OutDir, in.rel)
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name)) + ".svg"
+	if args.Svgz {
+		name += "z"
+	}
+	return name
+}
+
+// Draw each diagram of args.inputPaths to its SVG file, 'args.Jobs' at a time; then
+// report the problems found in each, and a summary, and exit if any was an error.
+func batch(args *Args, dialect *svg.Dialect, markBindingMap svg.MarkBindingMap,
+	cssInclude []internal.NamedReadSeeker) {
+
+	if status := runBatch(args, dialect, markBindingMap, cssInclude, os.Stderr); status > 0 {
+		os.Exit(status)
+	}
+}
+
+// As batch(), reporting to 'stderr', but return the exit status rather than exit.
+// An input that cannot be drawn fails alone: the others are drawn regardless.
+func runBatch(args *Args, dialect *svg.Dialect, markBindingMap svg.MarkBindingMap,
+	cssInclude []internal.NamedReadSeeker, stderr io.Writer) (status int) {
+
+	inputs := expandInputs(args)
+
+	outputs := make(map[string]string)
+	for i, in := range inputs {
+		if len(in.diags) > 0 {
+			continue
+		}
+		out := in.output(args)
+		if other, found := outputs[out]; found {
+			inputs[i].diags = []svg.Diagnostic{{
+				File:     in.path,
+				Severity: svg.SeverityError,
+				Code:     codeWrite,
+				Message:  fmt.Sprintf("would overwrite %s, drawn from %s", out, other),
+			}}
+			continue
+		}
+		outputs[out] = in.path
+	}
+
+	// X  Each SVG reads the stylesheets anew, so each is given readers of its own.
+	type cssFile struct {
+		name    string
+		content []byte
+	}
+	var cssFiles []cssFile
+	for _, r := range cssInclude {
+		_, _ = r.Seek(0, 0)
+		cssFiles = append(cssFiles, cssFile{r.Name(), internal.ReadFileAll(r)})
+	}
+	colorsOnlyBytes := svg.ColorsOnlyCssFileContent(
+		args.SvgColorLightScheme,
+		args.SvgColorDarkScheme)
+
+	// X  Exits on any error of the stylesheets, before a worker can.
+	newConfig(args, dialect.ReservedSet, markBindingMap)
+
+	draw := func(in batchInput) (diags []svg.Diagnostic) {
+		if len(in.diags) > 0 {
+			return in.diags
+		}
+		src, err := os.ReadFile(in.path)
+		if err != nil {
+			d := svg.AsDiagnostic(err, svg.CodeIO)
+			d.File = in.path
+			return []svg.Diagnostic{d}
+		}
+		config := newConfig(args, dialect.ReservedSet, markBindingMap)
+		canvas := dialect.NewCanvas(&config, internal.NewNamedBytesReader(src, in.path))
+		var css []internal.NamedReadSeeker
+		for _, f := range cssFiles {
+			css = append(css, internal.NewNamedBytesReader(f.content, f.name))
+		}
+		var buf bytes.Buffer
+		var dst io.Writer = &buf
+		var gz *gzip.Writer
+		if args.Svgz {
+			gz = gzip.NewWriter(&buf)
+			dst = gz
+		}
+		svg.WriteCanvas(&config, canvas, args.IncludeDefaultCSS, colorsOnlyBytes, css, dst)
+		if gz != nil {
+			_ = gz.Close()
+		}
+		diags = config.Diagnostics
+		for i := range diags {
+			if len(diags[i].File) == 0 {
+				diags[i].File = in.path
+			}
+		}
+
+		// X  As for a single diagram, the SVG is written regardless of problems found.
+		out := in.output(args)
+		err = os.MkdirAll(filepath.Dir(out), 0o777)
+		if err == nil {
+			err = os.WriteFile(out, buf.Bytes(), 0o666)
+		}
+		if err != nil {
+			diags = append(diags, svg.Diagnostic{
+				File:     out,
+				Severity: svg.SeverityError,
+				Code:     codeWrite,
+				Message:  err.Error(),
+			})
+		}
+		return
+	}
+
+	results := make([][]svg.Diagnostic, len(inputs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(args.Jobs, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = draw(inputs[i])
+			}
+		}()
+	}
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Reported in the order of 'inputs', whatever the order of completion.
+	var diags []svg.Diagnostic
+	var failed []string
+	for i, found := range results {
+		diags = append(diags, found...)
+		for _, d := range found {
+			if d.Severity == svg.SeverityError {
+				failed = append(failed, inputs[i].path)
+				break
+			}
+		}
+	}
+	status = fprintDiagnostics(stderr, args, diags)
+	if args.Diagnostics == "text" {
+		fmt.Fprintf(stderr, "goat: %d of %d diagrams drawn without error",
+			len(inputs)-len(failed), len(inputs))
+		if len(failed) > 0 {
+			fmt.Fprintf(stderr, "; failed:\n\t%s", strings.Join(failed, "\n\t"))
+		}
+		fmt.Fprintln(stderr)
+	}
+	return
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/blampe/goat/svg"
+)
+
+func TestExpandInputs(t *testing.T) {
+	c := qt.New(t)
+
+	root := c.TempDir()
+	for _, name := range []string{
+		"top.txt",
+		"docs/a.txt",
+		"docs/b.md",
+		"docs/_draft/c.txt",
+		"docs/sub/d.txt",
+		"docs/sub/e.goat",
+	} {
+		name = filepath.Join(root, name)
+		c.Assert(os.MkdirAll(filepath.Dir(name), 0o777), qt.IsNil)
+		c.Assert(os.WriteFile(name, []byte("-\n"), 0o666), qt.IsNil)
+	}
+	docs := filepath.Join(root, "docs")
+
+	args := &Args{
+		inputPaths: []string{docs, filepath.Join(root, "*.txt"), filepath.Join(docs, "a.txt")},
+		Include:    []string{"*.txt", "*.goat"},
+		Exclude:    []string{"_*"},
+		OutDir:     "out",
+	}
+	inputs := expandInputs(args)
+	var outputs []string
+	for _, in := range inputs {
+		outputs = append(outputs, filepath.ToSlash(in.output(args)))
+	}
+	c.Assert(outputs, qt.DeepEquals, []string{
+		"out/docs/a.svg",
+		"out/docs/sub/d.svg",
+		"out/docs/sub/e.svg",
+		"out/top.svg",
+	})
+
+	// Named relative to the current directory, the path named is mirrored whole.
+	t.Chdir(root)
+	args.inputPaths = []string{"docs/sub", "./top.txt"}
+	outputs = nil
+	for _, in := range expandInputs(args) {
+		outputs = append(outputs, filepath.ToSlash(in.output(args)))
+	}
+	c.Assert(outputs, qt.DeepEquals, []string{
+		"out/docs/sub/d.svg",
+		"out/docs/sub/e.svg",
+		"out/top.svg",
+	})
+	args.inputPaths = []string{docs, filepath.Join(root, "*.txt"), filepath.Join(docs, "a.txt")}
+
+	// Excluded by path within the directory named.
+	args.Exclude = []string{"sub/*.goat", "_draft"}
+	args.OutDir = ""
+	args.Svgz = true
+	inputs = expandInputs(args)
+	c.Assert(inputs, qt.HasLen, 3)
+	c.Assert(inputs[1].output(args), qt.Equals, filepath.Join(docs, "sub", "d.svgz"))
+
+	// -include applies only within directories, not to files named or matched.
+	args.inputPaths = []string{filepath.Join(docs, "*.md"), filepath.Join(root, "*.none")}
+	inputs = expandInputs(args)
+	c.Assert(inputs, qt.HasLen, 2)
+	c.Assert(inputs[0].rel, qt.Equals, "b.md")
+	c.Assert(inputs[0].diags, qt.HasLen, 0)
+	c.Assert(inputs[1].diags, qt.HasLen, 1)
+	c.Assert(inputs[1].diags[0].Error(), qt.Equals,
+		filepath.Join(root, "*.none")+": error: no files match [io/read]")
+}
+
+func TestBatch(t *testing.T) {
+	c := qt.New(t)
+
+	root := c.TempDir()
+	for name, content := range map[string]string{
+		"good.txt":      "+--+\n",
+		"docs/bad.txt":  "a\tb\n",
+		"good.md":       "-->\n",
+	} {
+		name = filepath.Join(root, name)
+		c.Assert(os.MkdirAll(filepath.Dir(name), 0o777), qt.IsNil)
+		c.Assert(os.WriteFile(name, []byte(content), 0o666), qt.IsNil)
+	}
+	dialect, err := svg.LookupDialect("ascii")
+	c.Assert(err, qt.IsNil)
+	outDir := filepath.Join(root, "out")
+	path := func(name string) string { return filepath.Join(root, name) }
+	args := &Args{
+		inputPaths: []string{
+			path("good.txt"), path("nonexist.txt"), path("docs"), path("good.md"),
+		},
+		Include:             []string{"*.txt"},
+		OutDir:              outDir,
+		Svgz:                true,
+		Jobs:                2,
+		Dialect:             "ascii",
+		Diagnostics:         "text",
+		IncludeDefaultCSS:   true,
+		SvgColorLightScheme: "#000",
+		SvgColorDarkScheme:  "#FFF",
+		Geometry:            svg.DefaultGeometry,
+	}
+
+	// A file missing, in error, or colliding with another fails alone, in its place.
+	var stderr bytes.Buffer
+	status := runBatch(args, dialect, make(svg.MarkBindingMap), nil, &stderr)
+	c.Assert(status, qt.Equals, 5)
+	c.Assert(stderr.String(), qt.Equals, ""+
+		path("nonexist.txt")+": error: no such file or directory [io/read]\n"+
+		path("docs/bad.txt")+":1:2: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]\n"+
+		path("good.md")+": error: would overwrite "+filepath.Join(outDir, "good.svgz")+
+		", drawn from "+path("good.txt")+" [io/write]\n"+
+		"goat: 1 of 4 diagrams drawn without error; failed:\n"+
+		"\t"+path("nonexist.txt")+"\n"+
+		"\t"+path("docs/bad.txt")+"\n"+
+		"\t"+path("good.md")+"\n")
+
+	// Drawn regardless of problems found, as a single diagram would be.
+	names, _ := filepath.Glob(filepath.Join(outDir, "*.svgz"))
+	nested, _ := filepath.Glob(filepath.Join(outDir, "*", "*.svgz"))
+	c.Assert(append(nested, names...), qt.DeepEquals, []string{
+		filepath.Join(outDir, "docs", "bad.svgz"), filepath.Join(outDir, "good.svgz"),
+	})
+	f, err := os.Open(filepath.Join(outDir, "good.svgz"))
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	c.Assert(err, qt.IsNil)
+	content, err := io.ReadAll(gz)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(content), qt.Contains, `d="M 0,0 L 24,0"`)
+
+	// Without the failures, the status is 0.
+	args.inputPaths = []string{path("good.txt")}
+	stderr.Reset()
+	c.Assert(runBatch(args, dialect, make(svg.MarkBindingMap), nil, &stderr), qt.Equals, 0)
+	c.Assert(stderr.String(), qt.Equals, "goat: 1 of 1 diagrams drawn without error\n")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/blampe/goat/svg"
+)
+
+// Exit statuses, by class of the most severe problem reported.
+// X  Status 1 remains that of log.Fatal(), and 2 that of package flag for a bad option.
+var exitStatus = map[string]int{
+	"input": 3,
+	"css":   4,
+	"io":    5,
+	"lint":  6,
+}
+
+// Print 'diags' to standard error in the format of -diagnostics, notes only if -v;
+// then if any is an error, or with -lint also a warning, exit with the status of its
+// class, the highest if several.
+func reportDiagnostics(args *Args, diags []svg.Diagnostic) {
+	if status := printDiagnostics(args, diags); status > 0 {
+		os.Exit(status)
+	}
+}
+
+// As reportDiagnostics(), but return the exit status rather than exit.
+func printDiagnostics(args *Args, diags []svg.Diagnostic) (status int) {
+	return fprintDiagnostics(os.Stderr, args, diags)
+}
+
+// As printDiagnostics(), but print to 'w'.
+func fprintDiagnostics(w io.Writer, args *Args, diags []svg.Diagnostic) (status int) {
+	if !args.Verbose {
+		diags = slices.DeleteFunc(slices.Clone(diags), func(d svg.Diagnostic) bool {
+			return d.Severity == svg.SeverityNote
+		})
+	}
+	if len(diags) == 0 && args.Diagnostics != "sarif" {
+		return 0
+	}
+	var v any = diags
+	switch args.Diagnostics {
+	case "sarif":
+		v = newSarifLog(diags)
+		fallthrough
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			panic(err)
+		}
+	default:
+		for _, d := range diags {
+			fmt.Fprintln(w, d.Error())
+		}
+	}
+	for _, d := range diags {
+		if d.Severity == svg.SeverityError || args.Lint && d.Severity == svg.SeverityWarning {
+			status = max(status, exitStatus[d.Class()], 1)
+		}
+	}
+	return
+}
+
+// The subset of SARIF 2.1.0 read by code-scanning UIs.
+//
+//	https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region *sarifRegion `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+)
+
+func newSarifLog(diags []svg.Diagnostic) sarifLog {
+	run := sarifRun{
+		Results: []sarifResult{},
+	}
+	run.Tool.Driver.Name = "goat"
+	run.Tool.Driver.InformationURI = "https://github.com/blampe/goat"
+	run.Tool.Driver.Rules = []sarifRule{}
+	ruleSeen := make(map[string]bool)
+	for _, d := range diags {
+		if !ruleSeen[d.Code] {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: d.Code})
+			ruleSeen[d.Code] = true
+		}
+		result := sarifResult{
+			RuleID:  d.Code,
+			Level:   d.Severity.String(),
+			Message: sarifMessage{Text: d.Message},
+		}
+		// X  SARIF requires a location be an artifact; one read from a pipe has none.
+		if len(d.File) > 0 {
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = d.File
+			if d.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   d.Line,
+					StartColumn: d.Col,
+				}
+			}
+			result.Locations = append(result.Locations, loc)
+		}
+		run.Results = append(run.Results, result)
+	}
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blampe/goat/internal/fence"
+	"github.com/blampe/goat/svg"
+)
+
+// Code of Diagnostics for ill-formed goat:begin ... goat:end regions.
+const codeRegion = "input/extract-region"
+
+// Lines beginning and ending a region: any indentation, then a comment leader if
+// any, then the marker.
+var (
+	regionBegin = regexp.MustCompile(`^(\s*(//|#|--|/\*|\*)?\s*)goat:begin\s+(\S+)(.*)$`)
+	regionEnd   = regexp.MustCompile(`^\s*(//|#|--|\*)?\s*goat:end\b`)
+)
+
+// Draw the diagrams found in comments of source files, each to an SVG file beside its
+// source.
+func extractMain(argv []string) {
+	var args Args
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	subcommandFlags(fs, &args)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: %s extract [flags] FILE ...
+
+Find in each FILE the diagrams written within comments, between lines marking a
+region:
+
+    // goat:begin NAME {css=embed:palette/earth.css, dialect=utf8}
+    //     +-----+     +-----+
+    //     | cmd |---->| svg |
+    //     +-----+     +-----+
+    // goat:end
+
+and draw each to NAME.svg in the directory of FILE.  Options follow NAME as in a
+fence of 'goat markdown'.  Comment leaders "//", "#" and "--" are stripped from
+each line, as is a leading "*" if the begin line has one, within /* */; then
+the indentation common to all lines.  For use from a Go source file:
+
+    //go:generate goat extract $GOFILE
+
+`,
+			os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(argv)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var diags []svg.Diagnostic
+	for _, name := range fs.Args() {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		diags = append(diags, extract(src, name, &args)...)
+	}
+	reportDiagnostics(&args, diags)
+}
+
+// A diagram found by scanRegions().
+type region struct {
+	name string
+
+	// Line of the source, counting from 1, of the goat:begin marker.
+	line int
+
+	// Options following the name.
+	info string
+
+	// Lines of the diagram, stripped of the comment leader and common indentation,
+	// and the count of bytes, all ASCII, stripped from each.
+	lines    []string
+	stripped []int
+}
+
+// Draw the diagrams of the regions of 'src', the content of file 'filename'.
+func extract(src []byte, filename string, args *Args) (diags []svg.Diagnostic) {
+	base := svg.RenderOptions{
+		Dialect:     args.Dialect,
+		LightScheme: args.SvgColorLightScheme,
+		DarkScheme:  args.SvgColorDarkScheme,
+	}
+	dir := filepath.Dir(filename)
+	regions, diags := scanRegions(src, filename)
+	for _, r := range regions {
+		errorAt := func(format string, a ...any) {
+			diags = append(diags, svg.Diagnostic{
+				File:     filename,
+				Line:     r.line,
+				Severity: svg.SeverityError,
+				Code:     codeRegion,
+				Message:  fmt.Sprintf(format, a...),
+			})
+		}
+		opts, _, err := fence.ParseInfo("goat " + r.info)
+		if err != nil {
+			errorAt("%v", err)
+			continue
+		}
+		var svgBuf bytes.Buffer
+		diagram := strings.Join(r.lines, "\n") + "\n"
+		found := fence.Render(&svgBuf, []byte(diagram), opts, base, fence.UnconfinedDir(dir))
+		for _, d := range found {
+			d.File = filename
+			if d.Line > 0 && d.Line <= len(r.lines) {
+				d.Col += r.stripped[d.Line-1]
+				d.Line += r.line
+			} else {
+				d.Line = r.line
+			}
+			diags = append(diags, d)
+		}
+		if svgBuf.Len() == 0 {
+			continue
+		}
+		out := filepath.Join(dir, r.name + ".svg")
+		// X  Left untouched if unchanged, lest 'go generate' make needless work for make(1).
+		if old, err := os.ReadFile(out); err == nil && bytes.Equal(old, svgBuf.Bytes()) {
+			continue
+		}
+		if err := os.WriteFile(out, svgBuf.Bytes(), 0o666); err != nil {
+			errorAt("%v", err)
+		}
+	}
+	return
+}
+
+// Find the goat:begin ... goat:end regions of 'src'.
+func scanRegions(src []byte, filename string) (regions []region, diags []svg.Diagnostic) {
+	errorAt := func(line int, format string, a ...any) {
+		diags = append(diags, svg.Diagnostic{
+			File:     filename,
+			Line:     line,
+			Severity: svg.SeverityError,
+			Code:     codeRegion,
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+	var (
+		open   *region
+		leader string
+		seen   = make(map[string]int)
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	scanner.Buffer(nil, len(src)+1)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if m := regionBegin.FindStringSubmatch(line); m != nil {
+			if open != nil {
+				errorAt(lineNumber, "goat:begin %s within region %s, begun on line %d",
+					m[3], open.name, open.line)
+				continue
+			}
+			name := m[3]
+			// X  Written beside the source, so must not name a file elsewhere.
+			if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+				errorAt(lineNumber, "region name %q contains a path separator, or is \".\" or \"..\"", name)
+				continue
+			}
+			// X  Drawn, it would overwrite the SVG of the first.
+			if previous, found := seen[name]; found {
+				errorAt(lineNumber, "region %s already begun on line %d", name, previous)
+				continue
+			}
+			seen[name] = lineNumber
+			open = &region{name: name, line: lineNumber, info: strings.TrimSpace(m[4])}
+			// Within /* */, only a leading "*" is stripped.
+			leader = m[2]
+			if leader == "/*" {
+				leader = ""
+			}
+			continue
+		}
+		if open == nil {
+			continue
+		}
+		if regionEnd.MatchString(line) {
+			finishRegion(open)
+			regions = append(regions, *open)
+			open = nil
+			continue
+		}
+		n := len(line)
+		if len(leader) > 0 {
+			trimmed := strings.TrimLeft(line, " \t")
+			line = strings.TrimPrefix(trimmed, leader)
+		}
+		open.lines = append(open.lines, line)
+		open.stripped = append(open.stripped, n - len(line))
+	}
+	if open != nil {
+		errorAt(open.line, "goat:begin %s never ended by goat:end", open.name)
+	}
+	return
+}
+
+// Remove from the lines of 'r' the indentation common to all not blank.
+func finishRegion(r *region) {
+	var common string
+	first := true
+	for _, line := range r.lines {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			common, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	for i, line := range r.lines {
+		if len(strings.TrimSpace(line)) == 0 {
+			r.lines[i] = ""
+			continue
+		}
+		r.lines[i] = line[len(common):]
+		r.stripped[i] += len(common)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestScanRegions(t *testing.T) {
+	c := qt.New(t)
+
+	src := `package x
+
+// goat:begin arch {dialect=utf8}
+//	+--+
+//
+//	  |
+// goat:end
+
+	/*
+	 * goat:begin star
+	 *   *--*
+	 * goat:end
+	 */
+
+# goat:begin hash
+#  -->
+# goat:end
+/* goat:begin block
+   *
+   goat:end */
+-- goat:begin dashes
+`
+	regions, diags := scanRegions([]byte(src), "x.go")
+	c.Assert(regions, qt.HasLen, 4)
+
+	c.Assert(regions[0].name, qt.Equals, "arch")
+	c.Assert(regions[0].line, qt.Equals, 3)
+	c.Assert(regions[0].info, qt.Equals, "{dialect=utf8}")
+	c.Assert(regions[0].lines, qt.DeepEquals, []string{"+--+", "", "  |"})
+	c.Assert(regions[0].stripped, qt.DeepEquals, []int{3, 2, 3})
+
+	c.Assert(regions[1].lines, qt.DeepEquals, []string{"*--*"})
+	c.Assert(regions[2].lines, qt.DeepEquals, []string{"-->"})
+	c.Assert(regions[3].lines, qt.DeepEquals, []string{"*"})
+
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Error(), qt.Equals,
+		"x.go:21: error: goat:begin dashes never ended by goat:end [input/extract-region]")
+}
+
+func TestExtract(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	filename := filepath.Join(dir, "x.go")
+	src := "package x\n" +
+		"\n" +
+		"// goat:begin box\n" +
+		"//   +--+\n" +
+		"// goat:end\n" +
+		"\n" +
+		"// goat:begin tabbed\n" +
+		"//   ok\n" +
+		"//   a\tb\n" +
+		"// goat:end\n" +
+		"\n" +
+		"// goat:begin ../evil\n" +
+		"//   -->\n" +
+		"// goat:end\n" +
+		"\n" +
+		"// goat:begin box\n" +
+		"//   -->\n" +
+		"// goat:end\n" +
+		"\n" +
+		"// goat:begin ..\n" +
+		"//   -->\n" +
+		"// goat:end\n" +
+		"\n" +
+		"// goat:begin a..b\n" +
+		"//   o\n" +
+		"// goat:end\n"
+	args := &Args{Dialect: "ascii", SvgColorLightScheme: "#000", SvgColorDarkScheme: "#FFF"}
+	diags := extract([]byte(src), filename, args)
+
+	// Located within the source, past the comment leader and indentation stripped.
+	c.Assert(diags, qt.HasLen, 4)
+	c.Assert(diags[0].Error(), qt.Equals, filename+`:12: error: region name "../evil" `+
+		`contains a path separator, or is "." or ".." [input/extract-region]`)
+	c.Assert(diags[1].Error(), qt.Equals, filename+
+		":16: error: region box already begun on line 3 [input/extract-region]")
+	c.Assert(diags[2].Error(), qt.Equals, filename+`:20: error: region name ".." `+
+		`contains a path separator, or is "." or ".." [input/extract-region]`)
+	c.Assert(diags[3].Error(), qt.Equals, filename+
+		":9:7: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+	names, _ := filepath.Glob(filepath.Join(dir, "*.svg"))
+	c.Assert(names, qt.DeepEquals, []string{
+		filepath.Join(dir, "a..b.svg"), filepath.Join(dir, "box.svg"), filepath.Join(dir, "tabbed.svg"),
+	})
+	_, err := os.Stat(filepath.Join(filepath.Dir(dir), "evil.svg"))
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+
+	// The first of regions of the same name is drawn, not the second.
+	box, err := os.ReadFile(names[1])
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(box), qt.Not(qt.Contains), "arrowhead")
+
+	// An SVG file unchanged is not written again.
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	c.Assert(os.Chtimes(names[1], past, past), qt.IsNil)
+	extract([]byte(src), filename, args)
+	info, err := os.Stat(names[1])
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.ModTime().Equal(past), qt.IsTrue)
+
+	extract([]byte(strings.Replace(src, "+--+", "+--+--+", 1)), filename, args)
+	info, err = os.Stat(names[1])
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.ModTime().After(past), qt.IsTrue)
+}
@@ -4,18 +4,23 @@ The command 'goat' provides CLI access to SVG images produced from text-art.
 package main
 
 import (
+	"compress/gzip"
 	"embed"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
 	"regexp"
 
 	"github.com/blampe/goat"
-	"github.com/blampe/goat/ascii"
 	"github.com/blampe/goat/css"
+	"github.com/blampe/goat/internal"
 	"github.com/blampe/goat/svg"
-	"github.com/blampe/goat/utf8"
+
+	// Dialects, registered for lookup by option -dialect.
+	_ "github.com/blampe/goat/ascii"
+	_ "github.com/blampe/goat/utf8"
 )
 
 func init() {
@@ -30,7 +35,23 @@ Executable %s in CWD %s:
 	log.SetPrefix(prefixStr)
 }
 
+// Subcommands, named by the first argument, each parsing the arguments following.
+var subcommands = map[string]func(argv []string){
+	"extract":  extractMain,
+	"html":     htmlMain,
+	"markdown": markdownMain,
+	"mdbook":   mdbookMain,
+
+	"pandoc-filter": pandocMain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if sub, found := subcommands[os.Args[1]]; found {
+			sub(os.Args[2:])
+			return
+		}
+	}
 	args, cssInclude, markBindingMap := ParseFlags()
 	colorsOnlyBytes := svg.ColorsOnlyCssFileContent(
 			args.SvgColorLightScheme,
@@ -41,24 +62,64 @@ func main() {
 		return
 	}
 
+	dialect, err := svg.LookupDialect(args.Dialect)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if args.Lint {
+		lint(&args, dialect, markBindingMap)
+		return
+	}
+	if len(args.inputPaths) > 0 {
+		batch(&args, dialect, markBindingMap, cssInclude)
+		return
+	}
+
 	input, output := OpenIO(&args)
 	// XX  Necessary if a call to os.Exit() before draining of output buffer is possible.
 	//	defer output.Close()   
 
-	var (
-		config svg.Config
-		canvas svg.AbstractCanvas
-	)
-	// XX  Simplify by following example of testlib.newCanvasFunc ?
-	if args.Utf8 {
-		config = newConfig(&args, utf8.ReservedSet, markBindingMap)
-		canvas = utf8.NewCanvas(&config, input)
-	} else {
-		config = newConfig(&args, ascii.ReservedSet, markBindingMap)
-		canvas = ascii.NewCanvas(&config, input)
+	config := newConfig(&args, dialect.ReservedSet, markBindingMap)
+	canvas := dialect.NewCanvas(&config, input)
+	var dst io.Writer = output
+	var gz *gzip.Writer
+	if args.Svgz {
+		gz = gzip.NewWriter(output)
+		dst = gz
 	}
 	svg.WriteCanvas(&config, canvas,
-		args.IncludeDefaultCSS, colorsOnlyBytes, cssInclude, output)
+		args.IncludeDefaultCSS, colorsOnlyBytes, cssInclude, dst)
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// X  The SVG is written regardless, drawing what could be made of the input.
+	reportDiagnostics(&args, config.Diagnostics)
+}
+
+// Report on each diagram of args.inputPaths, or if none, the input, without drawing.
+func lint(args *Args, dialect *svg.Dialect, markBindingMap svg.MarkBindingMap) {
+	inputs := []batchInput{{path: args.inputFilename}}
+	if len(args.inputPaths) > 0 {
+		inputs = expandInputs(args)
+	}
+	var diags []svg.Diagnostic
+	for _, in := range inputs {
+		if len(in.diags) > 0 {
+			diags = append(diags, in.diags...)
+			continue
+		}
+		input := os.Stdin
+		if len(in.path) > 0 {
+			input = internal.MustOpen(in.path)
+		}
+		config := newConfig(args, dialect.ReservedSet, markBindingMap)
+		svg.Lint(&config, dialect.NewCanvas(&config, input), args.LintWidth)
+		input.Close()
+		diags = append(diags, config.Diagnostics...)
+	}
+	reportDiagnostics(args, diags)
 }
 
 func dumpNames(efs embed.FS) {
@@ -84,9 +145,19 @@ func newConfig(args *Args, reservedSet goat.RuneSet, markBindingMap svg.MarkBind
 
 	config, err := svg.NewConfig(reservedSet, markBindingMap)
 	if err != nil {
-		log.Fatal(err)
+		reportDiagnostics(args,
+			[]svg.Diagnostic{svg.AsDiagnostic(err, svg.CodeCSS)})
 	}
 
 	config.LineFilter = regexp.MustCompile(args.LineFilterRegexpString)
+	config.Geometry = &args.Geometry
+	config.Frame = &args.Frame
+	config.Rows, config.Cols, config.Trim = args.Rows, args.Cols, args.Trim
+	config.MergeStrokes = !args.PerSegment
+	config.Compact = args.Compact
+	config.SourceMap = args.SourceMap
+	config.Classifier = args.Classifier
+	config.TabStop = args.TabStop
+	config.NFC = args.NFC
 	return
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/blampe/goat/internal/fence"
+	"github.com/blampe/goat/svg"
+)
+
+// Read an HTML document, writing it out again with each <pre class="goat">, or
+// <pre class="diagram"> as of Markdeep, replaced by an <svg> element.
+func htmlMain(argv []string) {
+	var args Args
+	fs := flag.NewFlagSet("html", flag.ExitOnError)
+	fs.StringVar(&args.outputFilename, "o", "", "Output filename (default: standard output)")
+	subcommandFlags(fs, &args)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: %s html [flags] [FILE.html]
+
+Read HTML, e.g. a Markdeep .md.html document, from FILE.html or standard input,
+and write it out again with each <pre> element of class "goat", "goat-" followed
+by a dialect name, or "diagram" replaced by an inline <svg> element.  Attributes
+data-dialect and data-css, a space-separated list of stylesheets relative to the
+directory of FILE.html, apply as do the options of a fence of 'goat markdown':
+
+    <pre class="goat" data-css="embed:palette/earth.css">
+    +--&gt; &lt;--+
+    </pre>
+
+Entities such as "&lt;" are decoded, and tags within the <pre> are ignored.
+All else of the document is copied unchanged.
+
+`,
+			os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(argv)
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		args.inputFilename = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	src, err := readInput(args.inputFilename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, diags := rewriteHTML(src, &args, filepath.Dir(args.inputFilename))
+	if len(args.outputFilename) > 0 {
+		err = os.WriteFile(args.outputFilename, out, 0o666)
+	} else {
+		_, err = os.Stdout.Write(out)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	reportDiagnostics(&args, diags)
+}
+
+// Return 'src' with its diagrams replaced, and the problems found in drawing them.
+// Stylesheets are sought relative to 'inDir'.
+func rewriteHTML(src []byte, args *Args, inDir string) (out []byte, diags []svg.Diagnostic) {
+	base := svg.RenderOptions{
+		Dialect:     args.Dialect,
+		LightScheme: args.SvgColorLightScheme,
+		DarkScheme:  args.SvgColorDarkScheme,
+		ScopeCSS:    true,
+	}
+	var buf bytes.Buffer
+	line := 1
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				log.Fatal(err)
+			}
+			break
+		}
+		// X  Copied, because TagName() and Text() rewrite the tokenizer's buffer.
+		raw := bytes.Clone(z.Raw())
+		if tt != html.StartTagToken {
+			buf.Write(raw)
+			line += bytes.Count(raw, []byte("\n"))
+			continue
+		}
+		opts, ok, err := preOptions(z)
+		if !ok {
+			buf.Write(raw)
+			line += bytes.Count(raw, []byte("\n"))
+			continue
+		}
+
+		// The whole element, kept in case the diagram cannot be drawn.
+		element := bytes.NewBuffer(raw)
+		var text bytes.Buffer
+		first := line + bytes.Count(raw, []byte("\n"))
+		for {
+			tt := z.Next()
+			if tt == html.ErrorToken {
+				break
+			}
+			element.Write(z.Raw())
+			if tt == html.TextToken {
+				text.Write(z.Text())
+			}
+			if name, _ := z.TagName(); tt == html.EndTagToken && string(name) == "pre" {
+				break
+			}
+		}
+		// As by an HTML parser, a newline just after the start tag is dropped.
+		diagram := text.Bytes()
+		for _, nl := range []string{"\r\n", "\n"} {
+			if after, found := bytes.CutPrefix(diagram, []byte(nl)); found {
+				diagram = after
+				first++
+				break
+			}
+		}
+
+		var svgBuf bytes.Buffer
+		if err != nil {
+			diags = append(diags, svg.Diagnostic{
+				File:     args.inputFilename,
+				Line:     first,
+				Severity: svg.SeverityError,
+				Code:     fence.CodeOption,
+				Message:  err.Error(),
+			})
+		} else {
+			found := fence.Render(&svgBuf, diagram, opts, base, fence.UnconfinedDir(inDir))
+			for _, d := range found {
+				d.File = args.inputFilename
+				if d.Line > 0 {
+					d.Line += first - 1
+				} else {
+					d.Line = first
+				}
+				diags = append(diags, d)
+			}
+		}
+		if svgBuf.Len() > 0 {
+			// X  Markdeep reads the document as Markdown.
+			buf.WriteString(strings.TrimSuffix(withoutBlankLines(svgBuf.String()), "\n"))
+		} else {
+			buf.Write(element.Bytes())
+		}
+		line += bytes.Count(element.Bytes(), []byte("\n"))
+	}
+	return buf.Bytes(), diags
+}
+
+// Interpret the attributes of the start tag just read by 'z': 'ok' is false unless it
+// is of a <pre> element of a class naming a diagram.
+func preOptions(z *html.Tokenizer) (opts fence.Options, ok bool, err error) {
+	name, hasAttr := z.TagName()
+	if string(name) != "pre" || !hasAttr {
+		return
+	}
+	var dialect, css string
+	for more := true; more; {
+		var key, value []byte
+		key, value, more = z.TagAttr()
+		switch string(key) {
+		case "class":
+			for _, class := range strings.Fields(string(value)) {
+				if class == "diagram" {
+					class = "goat"
+				}
+				if o, found, e := fence.ParseInfo(class); found {
+					opts, ok, err = o, found, e
+				}
+			}
+		case "data-dialect":
+			dialect = string(value)
+		case "data-css":
+			css = string(value)
+		}
+	}
+	if !ok || err != nil {
+		return
+	}
+	if len(dialect) > 0 {
+		opts.Dialect = dialect
+		if _, err = svg.LookupDialect(dialect); err != nil {
+			return
+		}
+	}
+	opts.CSS = strings.Fields(css)
+	return
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRewriteHTML(t *testing.T) {
+	c := qt.New(t)
+
+	args := &Args{Dialect: "ascii", inputFilename: "doc.html"}
+	src := `<p>a &amp; b</p>
+<pre class="diagram">
+  &lt;-<i>-</i>
+</pre>
+<pre>&lt;</pre>
+<pre class="goat" data-css="missing.css">x</pre>
+<pre class="goat">
+	</pre>
+`
+	out, diags := rewriteHTML([]byte(src), args, c.TempDir())
+	html := string(out)
+	c.Assert(html, qt.Matches, `(?s)<p>a &amp; b</p>\n<svg .*</svg>\n<pre>&lt;</pre>\n`+
+		`<pre class="goat" data-css="missing.css">x</pre>\n<svg .*</svg>\n`)
+	c.Assert(strings.Count(html, "<svg "), qt.Equals, 2)
+	c.Assert(strings.Count(html, "@scope {"), qt.Equals, 2)
+
+	// The newline after <pre> is dropped, but not the indentation; "&lt;" is an arrowhead.
+	first := html[:strings.Index(html, "</svg>")]
+	c.Assert(first, qt.Contains, `rotate(180, 16, 0)`)
+	c.Assert(first, qt.Contains, `d="M 16,0 L 32,0"`)
+
+	c.Assert(diags, qt.HasLen, 2)
+	c.Assert(diags[0].Class(), qt.Equals, "io")
+	c.Assert(diags[0].Line, qt.Equals, 6)
+	c.Assert(diags[1].Error(), qt.Equals,
+		"doc.html:8:1: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+}
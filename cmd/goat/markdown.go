@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blampe/goat/internal/fence"
+	"github.com/blampe/goat/svg"
+)
+
+// Options of subcommand 'markdown'.
+type markdownArgs struct {
+	Args
+
+	// Directory to which the SVG files are written; by default that of the output.
+	svgDir string
+
+	// Replace each fence by its <svg> element rather than an image link.
+	inline bool
+
+	// Follow each diagram by its source, within a <details> element.
+	details bool
+
+	// Stylesheets of all diagrams, preceding those named by each fence.
+	css []svg.CSSFile
+}
+
+// Read a Markdown document, writing it out again with each fence of info string "goat"
+// or "goat-DIALECT" replaced by the diagram drawn.
+func markdownMain(argv []string) {
+	var args markdownArgs
+	fs := flag.NewFlagSet("markdown", flag.ExitOnError)
+	fs.StringVar(&args.outputFilename, "o", "", "Output filename (default: standard output)")
+	fs.StringVar(&args.svgDir, "svgdir", "",
+		`Directory to which SVG files are written, each named for a hash of its content.
+(default: the directory of the output, or if standard output, of the input)`)
+	fs.BoolVar(&args.inline, "inline", false,
+		`Replace each fence by an inline <svg> element, rather than by an image link.`)
+	fs.BoolVar(&args.details, "details", false,
+		`Retain the source of each diagram after it, within a <details> element, fenced
+as "text".`)
+	subcommandFlags(fs, &args.Args)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: %s markdown [flags] [FILE.md]
+
+Read Markdown from FILE.md or standard input, and write it out again with each
+fenced code block of info string "goat", or "goat-" followed by a dialect name,
+replaced by the diagram drawn.  Options may follow the info string in braces:
+
+    `+"```"+`goat {css=embed:palette/earth.css, dialect=utf8, alt="Data flow"}
+
+Option "css" may be repeated; paths are relative to the directory of FILE.md.
+
+`,
+			os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(argv)
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		args.inputFilename = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	src, err := readInput(args.inputFilename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	inDir := filepath.Dir(args.inputFilename)
+	mdDir := inDir
+	if len(args.outputFilename) > 0 {
+		mdDir = filepath.Dir(args.outputFilename)
+	}
+	if len(args.svgDir) == 0 {
+		args.svgDir = mdDir
+	}
+
+	out, diags := rewriteMarkdown(src, &args, inDir, mdDir)
+	if len(args.outputFilename) > 0 {
+		err = os.WriteFile(args.outputFilename, out, 0o666)
+	} else {
+		_, err = os.Stdout.Write(out)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	reportDiagnostics(&args.Args, diags)
+}
+
+// Flags common to the subcommands drawing diagrams embedded in documents.
+func subcommandFlags(fs *flag.FlagSet, args *Args) {
+	fs.StringVar(&args.Dialect, "dialect", "ascii",
+		`Dialect of diagrams whose fence names none, one of: ` + strings.Join(svg.DialectNames(), ", "))
+	fs.StringVar(&args.SvgColorLightScheme, "svg-color-light-scheme", "#000",
+		`Color of drawing on a light background.`)
+	fs.StringVar(&args.SvgColorDarkScheme, "svg-color-dark-scheme", "#FFF",
+		`Color of drawing on a dark background.`)
+	fs.StringVar(&args.Diagnostics, "diagnostics", "text",
+		`Format of problems reported on standard error: "text", "json" or "sarif".`)
+	fs.BoolVar(&args.Verbose, "v", false,
+		`Report also notes.`)
+}
+
+// Read all of file 'name', or if empty, of standard input.
+func readInput(name string) ([]byte, error) {
+	if len(name) == 0 {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// Return 'src' with its goat fences replaced, and the problems found in drawing them,
+// located within 'src'.  Stylesheets are sought relative to 'inDir'; links to SVG files
+// are written relative to 'mdDir'.
+func rewriteMarkdown(src []byte, args *markdownArgs, inDir, mdDir string) (
+	out []byte, diags []svg.Diagnostic) {
+
+	base := svg.RenderOptions{
+		Dialect:     args.Dialect,
+		LightScheme: args.SvgColorLightScheme,
+		DarkScheme:  args.SvgColorDarkScheme,
+		CSS:         args.css,
+		ScopeCSS:    args.inline,
+	}
+	var buf bytes.Buffer
+	done := 0
+	for _, b := range fence.Blocks(src) {
+		opts, ok, err := fence.ParseInfo(b.Info)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			diags = append(diags, svg.Diagnostic{
+				File:     args.inputFilename,
+				Line:     b.Line - 1,
+				Col:      b.Indent + 1,
+				Severity: svg.SeverityError,
+				Code:     fence.CodeOption,
+				Message:  err.Error(),
+			})
+			continue
+		}
+
+		var svgBuf bytes.Buffer
+		found := fence.Render(&svgBuf, b.Content, opts, base, fence.UnconfinedDir(inDir))
+		for _, d := range found {
+			d.File = args.inputFilename
+			if d.Line > 0 {
+				d.Line += b.Line - 1
+				d.Col += b.Indent
+			} else {
+				d.Line = b.Line - 1
+			}
+			diags = append(diags, d)
+		}
+		if svgBuf.Len() == 0 {
+			continue
+		}
+
+		var repl bytes.Buffer
+		if args.inline {
+			repl.WriteString(withoutBlankLines(svgBuf.String()))
+		} else {
+			name, err := writeHashed(args.svgDir, svgBuf.Bytes())
+			if err != nil {
+				log.Fatal(err)
+			}
+			link, err := filepath.Rel(mdDir, name)
+			if err != nil {
+				link = name
+			}
+			fmt.Fprintf(&repl, "![%s](%s)\n", opts.Alt, filepath.ToSlash(link))
+		}
+		if args.details {
+			// X  Fenced as "text", lest it be drawn again by a second run.
+			marker := textFence(b.Content)
+			repl.WriteString("\n<details>\n<summary>Diagram source</summary>\n\n")
+			repl.WriteString(marker + "text\n")
+			repl.Write(b.Content)
+			if len(b.Content) > 0 && !bytes.HasSuffix(b.Content, []byte("\n")) {
+				repl.WriteString("\n")
+			}
+			repl.WriteString(marker + "\n\n</details>\n")
+		}
+
+		buf.Write(src[done:b.Start])
+		indent := strings.Repeat(" ", b.Indent)
+		for line := range strings.Lines(repl.String()) {
+			if line != "\n" {
+				buf.WriteString(indent)
+			}
+			buf.WriteString(line)
+		}
+		done = b.End
+	}
+	buf.Write(src[done:])
+	return buf.Bytes(), diags
+}
+
+// Return a fence of backticks longer than any opening a line of 'content', which
+// therefore cannot close it.
+func textFence(content []byte) string {
+	n := 3
+	for line := range strings.Lines(string(content)) {
+		line = strings.TrimLeft(line, " ")
+		run := len(line) - len(strings.TrimLeft(line, "`"))
+		n = max(n, run+1)
+	}
+	return strings.Repeat("`", n)
+}
+
+// Remove the blank lines from the <svg> element 's'.  Within Markdown, a blank line
+// would end the HTML block, returning the rest of the element to the parser.
+func withoutBlankLines(s string) string {
+	var b strings.Builder
+	for line := range strings.Lines(s) {
+		if len(strings.TrimSpace(line)) > 0 {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// Write 'content' to a file in 'dir' named for its SHA-256 hash, unless already there;
+// return the file's path.
+func writeHashed(dir string, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	name := filepath.Join(dir, "goat-" + hex.EncodeToString(sum[:8]) + ".svg")
+	if _, err := os.Stat(name); err == nil {
+		return name, nil
+	}
+	return name, os.WriteFile(name, content, 0o666)
+}
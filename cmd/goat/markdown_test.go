@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRewriteMarkdown(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	src := "# Title\n" +
+		"\n" +
+		"```goat {alt=\"Box\"}\n" +
+		"+--+\n" +
+		"```\n" +
+		"\n" +
+		"- item\n" +
+		"\n" +
+		"  ~~~goat\n" +
+		"  --->\n" +
+		"  a\tb\n" +
+		"  ~~~\n" +
+		"\n" +
+		"```goat {nonsense\n" +
+		"x\n" +
+		"```\n"
+
+	args := &markdownArgs{
+		Args:   Args{Dialect: "ascii", inputFilename: "doc.md"},
+		svgDir: filepath.Join(dir, "img"),
+	}
+	c.Assert(os.Mkdir(args.svgDir, 0o755), qt.IsNil)
+	out, diags := rewriteMarkdown([]byte(src), args, dir, filepath.Join(dir, "docs"))
+	md := string(out)
+
+	// Links are relative to the directory of the output.
+	c.Assert(md, qt.Matches, `(?s)# Title\n\n!\[Box\]\(\.\./img/goat-[0-9a-f]{16}\.svg\)\n\n- item\n\n`+
+		`  !\[\]\(\.\./img/goat-[0-9a-f]{16}\.svg\)\n\n`+"```goat \\{nonsense\nx\n```\n")
+	names, _ := filepath.Glob(filepath.Join(args.svgDir, "*.svg"))
+	c.Assert(names, qt.HasLen, 2)
+
+	// Located within the document: the TAB by the indentation of its fence.
+	c.Assert(diags, qt.HasLen, 2)
+	c.Assert(diags[0].Error(), qt.Equals,
+		"doc.md:11:4: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+	c.Assert(diags[1].Line, qt.Equals, 14)
+	c.Assert(diags[1].Col, qt.Equals, 1)
+
+	// Inline, each <svg> is indented as was its fence, with no blank line to end it.
+	args.inline, args.details = true, true
+	out, _ = rewriteMarkdown([]byte(src), args, dir, dir)
+	md = string(out)
+	c.Assert(strings.Count(md, "<svg "), qt.Equals, 2)
+	c.Assert(md, qt.Contains, "<style type=\"text/css\" source-text-origin=\"source-independent defaults: shared by ASCII and UTF-8\">\n    @scope {\n        svg {\n")
+	for _, svg := range regexp.MustCompile(`(?s)<svg .*?</svg>`).FindAllString(md, -1) {
+		c.Assert(svg, qt.Not(qt.Contains), "\n\n")
+	}
+	c.Assert(regexp.MustCompile(`(?m)^  <svg `).FindAllString(md, -1), qt.HasLen, 1)
+
+	// The source retained is no longer a goat fence, so is left alone by a second run.
+	c.Assert(md, qt.Contains, "<summary>Diagram source</summary>\n\n```text\n+--+\n```\n\n</details>\n")
+	c.Assert(md, qt.Contains, "  ```text\n  --->\n")
+	again, _ := rewriteMarkdown(out, args, dir, dir)
+	c.Assert(strings.Count(string(again), "<svg "), qt.Equals, 2)
+
+	c.Assert(textFence([]byte("a\n ````\n")), qt.Equals, "`````")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/blampe/goat/internal/fence"
+	"github.com/blampe/goat/svg"
+)
+
+// Act as a preprocessor of mdBook, drawing the goat fences of each chapter as inline
+// <svg> elements.
+//
+//	https://rust-lang.github.io/mdBook/for_developers/preprocessors.html
+//
+// Its table of book.toml may name stylesheets for all diagrams, relative to the root
+// of the book, and the dialect of fences naming none:
+//
+//	[preprocessor.goat]
+//	command = "goat mdbook"
+//	css = ["theme/diagrams.css", "embed:palette/earth.css"]
+//	dialect = "utf8"
+func mdbookMain(argv []string) {
+	if len(argv) > 0 && argv[0] == "supports" {
+		if len(argv) > 1 && mdbookSupports(argv[1]) {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+	if len(argv) > 0 {
+		fmt.Fprintf(os.Stderr, `Usage: %[1]s mdbook
+       %[1]s mdbook supports RENDERER
+
+Act as a preprocessor of mdBook, reading the book as JSON on standard input and
+writing it to standard output with the goat fences of each chapter drawn.
+`,
+			os.Args[0])
+		os.Exit(2)
+	}
+
+	diags, err := mdbookPreprocess(os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reportDiagnostics(&Args{Diagnostics: "text"}, diags)
+}
+
+// Whether mdBook's 'renderer' can use the book preprocessed: inline SVG is of use to
+// no renderer but HTML.
+func mdbookSupports(renderer string) bool {
+	return renderer == "html"
+}
+
+// The [preprocessor.goat] table of book.toml.
+type mdbookConfig struct {
+	CSS     []string `json:"css"`
+	Dialect string   `json:"dialect"`
+}
+
+// Read from 'in' the JSON array [context, book] sent by mdBook, and write to 'out' the
+// book with its chapters' goat fences replaced.
+//
+// X  The book is handled as untyped JSON, so that fields unknown to us survive.
+func mdbookPreprocess(in io.Reader, out io.Writer) (diags []svg.Diagnostic, err error) {
+	var input [2]json.RawMessage
+	if err := json.NewDecoder(in).Decode(&input); err != nil {
+		return nil, fmt.Errorf("reading mdBook input: %w", err)
+	}
+	var ctx struct {
+		Root   string `json:"root"`
+		Config struct {
+			Book struct {
+				Src string `json:"src"`
+			} `json:"book"`
+			Preprocessor struct {
+				Goat mdbookConfig `json:"goat"`
+			} `json:"preprocessor"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(input[0], &ctx); err != nil {
+		return nil, fmt.Errorf("reading mdBook context: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(input[1]))
+	dec.UseNumber()
+	var book any
+	if err := dec.Decode(&book); err != nil {
+		return nil, fmt.Errorf("reading mdBook book: %w", err)
+	}
+
+	conf := ctx.Config.Preprocessor.Goat
+	args := markdownArgs{inline: true}
+	args.Dialect = conf.Dialect
+	if len(args.Dialect) == 0 {
+		args.Dialect = "ascii"
+	}
+	if _, err := svg.LookupDialect(args.Dialect); err != nil {
+		return nil, fmt.Errorf("[preprocessor.goat] dialect: %w", err)
+	}
+	args.SvgColorLightScheme, args.SvgColorDarkScheme = "#000", "#FFF"
+	args.css, err = fence.LoadCSS(conf.CSS, fence.UnconfinedDir(ctx.Root))
+	if err != nil {
+		return nil, fmt.Errorf("[preprocessor.goat] css: %w", err)
+	}
+	src := ctx.Config.Book.Src
+	if len(src) == 0 {
+		src = "src"
+	}
+	srcDir := filepath.Join(ctx.Root, src)
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			if chapter, ok := v["Chapter"].(map[string]any); ok {
+				content, _ := chapter["content"].(string)
+				path, _ := chapter["path"].(string)
+				args.inputFilename = filepath.Join(srcDir, path)
+				inDir := filepath.Dir(args.inputFilename)
+				rewritten, found := rewriteMarkdown([]byte(content), &args, inDir, inDir)
+				chapter["content"] = string(rewritten)
+				diags = append(diags, found...)
+				walk(chapter["sub_items"])
+				return
+			}
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(book)
+
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return diags, enc.Encode(book)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMdbookSupports(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(mdbookSupports("html"), qt.IsTrue)
+	c.Assert(mdbookSupports("markdown"), qt.IsFalse)
+}
+
+// Runs mdbookPreprocess() on testdata/mdbook.json, as would mdBook.
+func TestMdbookPreprocess(t *testing.T) {
+	c := qt.New(t)
+
+	in, err := os.ReadFile("testdata/mdbook.json")
+	c.Assert(err, qt.IsNil)
+	var out bytes.Buffer
+	diags, err := mdbookPreprocess(bytes.NewReader(in), &out)
+	c.Assert(err, qt.IsNil)
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Error(), qt.Equals, "testdata/mdbook/src/intro/nested.md:6:1: "+
+		"error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+
+	var book map[string]any
+	c.Assert(json.Unmarshal(out.Bytes(), &book), qt.IsNil)
+	sections := book["sections"].([]any)
+	intro := sections[0].(map[string]any)["Chapter"].(map[string]any)
+	content := intro["content"].(string)
+
+	// Drawn as the dialect of [preprocessor.goat], with its stylesheet.
+	c.Assert(content, qt.Matches, `(?s)# Intro\n\n<svg .*</svg>\n`)
+	c.Assert(content, qt.Not(qt.Contains), "─</text>")
+	c.Assert(content, qt.Contains, `source-text-origin="testdata/mdbook/theme/diagrams.css"`)
+	c.Assert(content, qt.Contains, "stroke-width: 3;")
+
+	// Nested chapters are drawn too, a fence's dialect overriding that of the book.
+	nested := intro["sub_items"].([]any)[0].(map[string]any)["Chapter"].(map[string]any)
+	c.Assert(strings.Count(nested["content"].(string), "<svg "), qt.Equals, 2)
+	c.Assert(nested["content"], qt.Contains, `d="M 0,0 L 24,0"`)
+
+	// Fields unknown to us survive.
+	_, found := book["__non_exhaustive"]
+	c.Assert(found, qt.IsTrue)
+	c.Assert(sections[1], qt.Equals, "Separator")
+	c.Assert(sections[2], qt.DeepEquals, map[string]any{"PartTitle": "Reference"})
+	c.Assert(nested["parent_names"], qt.DeepEquals, []any{"Intro"})
+	c.Assert(nested["number"], qt.DeepEquals, []any{1.0, 1.0})
+
+	// A dialect unknown is an error of the whole book.
+	bad := bytes.Replace(in, []byte(`"dialect": "utf8"`), []byte(`"dialect": "no-such"`), 1)
+	_, err = mdbookPreprocess(bytes.NewReader(bad), &out)
+	c.Assert(err, qt.ErrorMatches, `\[preprocessor.goat\] dialect: .*`)
+}
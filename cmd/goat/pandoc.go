@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blampe/goat/internal/fence"
+	"github.com/blampe/goat/svg"
+)
+
+// Options of subcommand 'pandoc-filter'.
+type pandocArgs struct {
+	Args
+
+	// Output format of pandoc, as passed to filters.
+	format string
+
+	// Directory to which SVG files are written, for formats other than HTML.
+	imageDir string
+}
+
+// Act as a JSON filter of pandoc, drawing each CodeBlock of class "goat".
+//
+//	https://pandoc.org/filters.html
+func pandocMain(argv []string) {
+	var args pandocArgs
+	fs := flag.NewFlagSet("pandoc-filter", flag.ExitOnError)
+	fs.StringVar(&args.imageDir, "dir", ".",
+		`Directory to which SVG files are written, for output formats other than HTML,
+each named for a hash of its content.`)
+	subcommandFlags(fs, &args.Args)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: %s pandoc-filter [flags] [FORMAT]
+
+Act as a JSON filter of pandoc: read its AST on standard input, and write it to
+standard output with each CodeBlock of class "goat", or "goat-" followed by a
+dialect name, drawn.  Attributes "dialect", "css" (which may be repeated) and
+"alt" apply as for the options of a fence of 'goat markdown':
+
+    `+"```"+`{.goat dialect=utf8 css=embed:palette/earth.css}
+
+For FORMAT html and its kin, as passed by pandoc, a diagram is drawn as a RawBlock
+of inline SVG; otherwise as an Image of an SVG file written to -dir.
+Pandoc runs a filter given by path, so by a script such as:
+
+    #!/bin/sh
+    exec goat pandoc-filter "$@"
+
+`,
+			os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(argv)
+	switch fs.NArg() {
+	case 0:
+		args.format = "html"
+	case 1:
+		args.format = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	diags, err := pandocFilter(os.Stdin, os.Stdout, &args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reportDiagnostics(&args.Args, diags)
+}
+
+// Whether pandoc output 'format' is HTML, or a format embedding it.
+func isHTMLFormat(format string) bool {
+	// X  Strip any extensions e.g. "html5+smart".
+	if i := strings.IndexAny(format, "+-"); i > 0 {
+		format = format[:i]
+	}
+	switch format {
+	case "html", "html4", "html5", "chunkedhtml", "epub", "epub2", "epub3",
+		"revealjs", "s5", "slidy", "slideous", "dzslides":
+		return true
+	}
+	return false
+}
+
+// Read the AST of pandoc from 'in', writing it to 'out' with goat CodeBlocks replaced.
+//
+// X  The AST is handled as untyped JSON, so that nodes unknown to us survive.
+//    Diagnostics are located within the diagram, and by the identifier of the
+//    CodeBlock if any; pandoc records no line numbers of the source.
+func pandocFilter(in io.Reader, out io.Writer, args *pandocArgs) (diags []svg.Diagnostic, err error) {
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("reading pandoc AST: %w", err)
+	}
+	base := svg.RenderOptions{
+		Dialect:     args.Dialect,
+		LightScheme: args.SvgColorLightScheme,
+		DarkScheme:  args.SvgColorDarkScheme,
+	}
+	html := isHTMLFormat(args.format)
+	base.ScopeCSS = html
+	files := fence.UnconfinedDir(".")
+
+	// Return the replacement for a goat CodeBlock 'v', or nil.
+	replace := func(v map[string]any) map[string]any {
+		if v["t"] != "CodeBlock" {
+			return nil
+		}
+		c, _ := v["c"].([]any)
+		if len(c) != 2 {
+			return nil
+		}
+		text, _ := c[1].(string)
+		id, opts, ok, err := pandocOptions(c[0])
+		if !ok {
+			return nil
+		}
+		where := func(d svg.Diagnostic) svg.Diagnostic {
+			if len(id) > 0 {
+				d.File = "#" + id
+			}
+			return d
+		}
+		if err != nil {
+			diags = append(diags, where(svg.Diagnostic{
+				Severity: svg.SeverityError,
+				Code:     fence.CodeOption,
+				Message:  err.Error(),
+			}))
+			return nil
+		}
+		var svgBuf bytes.Buffer
+		for _, d := range fence.Render(&svgBuf, []byte(text), opts, base, files) {
+			diags = append(diags, where(d))
+		}
+		if svgBuf.Len() == 0 {
+			return nil
+		}
+		if html {
+			return map[string]any{
+				"t": "RawBlock",
+				"c": []any{"html", svgBuf.String()},
+			}
+		}
+		name, err := writeHashed(args.imageDir, svgBuf.Bytes())
+		if err != nil {
+			log.Fatal(err)
+		}
+		image := map[string]any{
+			"t": "Image",
+			"c": []any{
+				[]any{id, []any{}, []any{}},
+				pandocInlines(opts.Alt),
+				[]any{filepath.ToSlash(name), ""},
+			},
+		}
+		return map[string]any{
+			"t": "Para",
+			"c": []any{image},
+		}
+	}
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for i, e := range v {
+				if m, ok := e.(map[string]any); ok {
+					if r := replace(m); r != nil {
+						v[i] = r
+						continue
+					}
+				}
+				walk(e)
+			}
+		case map[string]any:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(doc)
+
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return diags, enc.Encode(doc)
+}
+
+// Interpret the Attr [identifier, classes, key-value pairs] of a CodeBlock: 'ok' is
+// false unless a class is "goat" or "goat-DIALECT".
+func pandocOptions(attr any) (id string, opts fence.Options, ok bool, err error) {
+	a, _ := attr.([]any)
+	if len(a) != 3 {
+		return
+	}
+	id, _ = a[0].(string)
+	classes, _ := a[1].([]any)
+	for _, class := range classes {
+		class, _ := class.(string)
+		if class == "goat" || strings.HasPrefix(class, "goat-") {
+			// X  Parsed as would be the info string of a fence of that language.
+			opts, ok, err = fence.ParseInfo(class)
+			break
+		}
+	}
+	if !ok || err != nil {
+		return
+	}
+	pairs, _ := a[2].([]any)
+	for _, pair := range pairs {
+		kv, _ := pair.([]any)
+		if len(kv) != 2 {
+			continue
+		}
+		key, _ := kv[0].(string)
+		value, _ := kv[1].(string)
+		switch key {
+		case "css":
+			opts.CSS = append(opts.CSS, value)
+		case "dialect":
+			opts.Dialect = value
+			if _, err = svg.LookupDialect(value); err != nil {
+				return
+			}
+		case "alt":
+			opts.Alt = value
+		default:
+			err = fmt.Errorf(`unknown attribute %q: expected "css", "dialect" or "alt"`, key)
+			return
+		}
+	}
+	return
+}
+
+// Return the text 's' as pandoc Inlines: a Str of each word, with a Space between,
+// as pandoc itself would read it.
+func pandocInlines(s string) []any {
+	inlines := []any{}
+	for i, word := range strings.Fields(s) {
+		if i > 0 {
+			inlines = append(inlines, map[string]any{"t": "Space"})
+		}
+		inlines = append(inlines, map[string]any{"t": "Str", "c": word})
+	}
+	return inlines
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// Runs pandocFilter() on testdata/pandoc.json, as would pandoc for 'format'.
+func filterFixture(c *qt.C, format string) (blocks []map[string]any, args *pandocArgs) {
+	in, err := os.Open("testdata/pandoc.json")
+	c.Assert(err, qt.IsNil)
+	defer in.Close()
+
+	args = &pandocArgs{format: format, imageDir: c.TempDir()}
+	args.Dialect = "ascii"
+	var out bytes.Buffer
+	diags, err := pandocFilter(in, &out, args)
+	c.Assert(err, qt.IsNil)
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Error(), qt.Equals,
+		"#tab:1:2: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+
+	var doc struct {
+		Version []int            `json:"pandoc-api-version"`
+		Blocks  []map[string]any `json:"blocks"`
+	}
+	c.Assert(json.Unmarshal(out.Bytes(), &doc), qt.IsNil)
+	c.Assert(doc.Version, qt.DeepEquals, []int{1, 23, 1})
+	return doc.Blocks, args
+}
+
+func TestPandocFilterHTML(t *testing.T) {
+	c := qt.New(t)
+
+	blocks, _ := filterFixture(c, "html5")
+	c.Assert(blocks, qt.HasLen, 5)
+	c.Assert(blocks[0]["t"], qt.Equals, "Header")
+	c.Assert(blocks[1]["t"], qt.Equals, "RawBlock")
+	raw := blocks[1]["c"].([]any)
+	c.Assert(raw[0], qt.Equals, "html")
+	c.Assert(raw[1], qt.Matches, `(?s)<svg .*</svg>\n`)
+	c.Assert(blocks[2]["t"], qt.Equals, "CodeBlock")
+
+	// Nested within a list.
+	item := blocks[3]["c"].([]any)[0].([]any)[0].(map[string]any)
+	c.Assert(item["t"], qt.Equals, "RawBlock")
+	c.Assert(item["c"].([]any)[1], qt.Contains, "palette/earth.css")
+}
+
+func TestPandocFilterImage(t *testing.T) {
+	c := qt.New(t)
+
+	blocks, args := filterFixture(c, "docx")
+	item := blocks[3]["c"].([]any)[0].([]any)[0].(map[string]any)
+	c.Assert(item["t"], qt.Equals, "Para")
+	image := item["c"].([]any)[0].(map[string]any)
+	c.Assert(image["t"], qt.Equals, "Image")
+	c.Assert(image["c"].([]any)[0].([]any)[0], qt.Equals, "box")
+	c.Assert(image["c"].([]any)[1], qt.DeepEquals, []any{
+		map[string]any{"t": "Str", "c": "A"},
+		map[string]any{"t": "Space"},
+		map[string]any{"t": "Str", "c": "box"},
+	})
+
+	target := image["c"].([]any)[2].([]any)[0].(string)
+	c.Assert(filepath.Dir(target), qt.Equals, filepath.ToSlash(args.imageDir))
+	content, err := os.ReadFile(target)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(content), qt.Contains, "<svg ")
+}
+
+func TestPandocOptions(t *testing.T) {
+	c := qt.New(t)
+
+	attr := []any{"d", []any{"goat"}, []any{[]any{"alt", "A  box"}, []any{"css", "a.css"}}}
+	id, opts, ok, err := pandocOptions(attr)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(id, qt.Equals, "d")
+	c.Assert(opts.CSS, qt.DeepEquals, []string{"a.css"})
+	c.Assert(pandocInlines(opts.Alt), qt.HasLen, 3)
+	c.Assert(pandocInlines(""), qt.DeepEquals, []any{})
+
+	// As would be an option of a fence.
+	attr[2] = []any{[]any{"width", "10"}}
+	_, _, ok, err = pandocOptions(attr)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, `unknown attribute "width": expected "css", "dialect" or "alt"`)
+
+	_, _, ok, _ = pandocOptions([]any{"", []any{"go"}, []any{[]any{"width", "10"}}})
+	c.Assert(ok, qt.IsFalse)
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 
 	"github.com/blampe/goat/css"
@@ -18,7 +19,10 @@ const (
 )
 
 type Args struct {
-	listEmbedded, Utf8, IncludeDefaultCSS bool
+	listEmbedded, IncludeDefaultCSS bool
+
+	// Name of a dialect registered with svg.RegisterDialect().
+	Dialect string
 
 	inputFilename,
 	outputFilename,
@@ -28,6 +32,41 @@ type Args struct {
 
 	// Default for all color fill, from command line.
 	SvgColorLightScheme, SvgColorDarkScheme string
+
+	Geometry svg.Geometry
+
+	Frame svg.Frame
+
+	Rows, Cols svg.Span
+	Trim bool
+
+	PerSegment bool
+
+	Compact, Svgz bool
+	SourceMap bool
+
+	Classifier svg.Classifier
+
+	// Format of problems reported on standard error: "text", "json" or "sarif".
+	Diagnostics string
+
+	// Report suspicious constructs of the diagrams 'inputPaths', or else of the input,
+	// rather than drawing; flag rows wider than LintWidth if non-zero.
+	Lint bool
+	LintWidth int
+
+	// Diagram files, directories and glob patterns named as arguments, to be drawn
+	// as a batch; see expandInputs().
+	inputPaths []string
+	Include, Exclude []string
+	OutDir string
+	Jobs int
+
+	// Report also Diagnostics of severity note.
+	Verbose bool
+
+	TabStop int
+	NFC bool
 }
 
 func ParseFlags() (
@@ -44,10 +83,18 @@ func ParseFlags() (
 Extract the contents of one by naming it on the command line of another
 invocation of goat, prefixed by "embed:"`)
 
-	flag.BoolVar(&args.Utf8, "utf8", false,
-		`Diagram input contains UTF-8 BOX characters.
+	flag.StringVar(&args.Dialect, "dialect", "ascii",
+		`Dialect of the diagram input, one of: ` + strings.Join(svg.DialectNames(), ", "))
+	flag.BoolFunc("utf8",
+		`Short for -dialect=utf8: diagram input contains UTF-8 BOX characters.
 Goat treats only these as graphics; ASCII characters regarded by Markdeep as
-coding for graphics are treated as ordinary text.`)
+coding for graphics are treated as ordinary text.`,
+		func(s string) error {
+			if s == "true" {
+				args.Dialect = "utf8"
+			}
+			return nil
+		})
 
 	// abort if this is 'false' and -sls or -sds has been specified
 	flag.BoolVar(&args.IncludeDefaultCSS, "defaultcss", true,
@@ -63,6 +110,135 @@ to a new file with pathname same except for replacement of .txt with .svg`)
 	flag.StringVar(&args.LineFilterRegexpString, "regexp", "",
 		"Discard any input lines that fail to match this regular expression.")
 
+	flag.Func("classifier",
+		`Rules for telling text from graphics in ASCII diagrams: "classic", deciding from
+the immediate neighbors of each character, or "words", which first treats as text
+any whitespace-delimited word containing a letter or digit not reserved for graphics,
+unless a line attaches to it.  (default "classic")`,
+		func(s string) (err error) {
+			args.Classifier, err = svg.ParseClassifier(s)
+			return
+		})
+
+	flag.StringVar(&args.Diagnostics, "diagnostics", "text",
+		`Format of problems found in the diagram or CSS, reported on standard error:
+"text", one per line as FILE:LINE:COL: SEVERITY: MESSAGE [CODE]; "json", an array
+of objects with those fields; or "sarif", a SARIF 2.1.0 log for code-scanning tools.
+On error, the exit status is 3 for the diagram input, 4 for CSS, or 5 for failure
+to read; with -lint, 6 for any warning.`)
+	flag.BoolVar(&args.Lint, "lint", false,
+		`Write no SVG, but report constructs likely drawn otherwise than intended: lines
+ending one space short of a joint, joints meeting no line, arrowheads attached
+to no line, letters drawn as graphics, unclosed or nested marks, and trailing
+whitespace; with -v, also joints with a single arm.  Diagram files may be named on
+the command line, as FILE.txt.`)
+	flag.IntVar(&args.LintWidth, "lint-width", 0,
+		`With -lint, also report rows wider than this many characters, if non-zero.`)
+
+	listFlag := func(list *[]string, name, usage string) {
+		flag.Func(name, usage,
+			func(s string) error {
+				if _, err := path.Match(s, ""); err != nil {
+					return err
+				}
+				*list = append(*list, s)
+				return nil
+			})
+	}
+	listFlag(&args.Include, "include",
+		`Glob pattern selecting by name the diagram files found within directories named
+as arguments; may be repeated.  (default "*.txt")`)
+	listFlag(&args.Exclude, "exclude",
+		`Glob pattern rejecting by name, or by path relative to the directory named as
+an argument, diagram files or directories; may be repeated.`)
+	flag.StringVar(&args.OutDir, "outdir", "",
+		`Directory to which the SVG files of diagrams named as arguments are written,
+mirroring each path named, and the tree beneath it; a path absolute or outside the
+current directory keeps only its base name.  (default: beside each diagram)`)
+	flag.IntVar(&args.Jobs, "jobs", runtime.NumCPU(),
+		`Number of diagrams named as arguments drawn concurrently.`)
+
+	flag.BoolVar(&args.Verbose, "v", false,
+		`Report also notes, e.g. where TABs were expanded by -tabstop.`)
+
+	flag.IntVar(&args.TabStop, "tabstop", 0,
+		`Expand each TAB of the input to spaces, up to the next column that is a multiple
+of this.  If 0, a TAB is an error.`)
+
+	flag.BoolVar(&args.NFC, "nfc", false,
+		`Normalize the input to Unicode NFC, so that a letter followed by combining accents
+is drawn as the single precomposed character, where one exists.`)
+
+	flag.Float64Var(&args.Geometry.CellWidth, "cell-width", svg.DefaultGeometry.CellWidth,
+		`Width in pixels of each character cell of the diagram: the advance width of the font.`)
+	flag.Float64Var(&args.Geometry.CellHeight, "cell-height", svg.DefaultGeometry.CellHeight,
+		`Height in pixels of each character cell of the diagram: the line height of the font.`)
+	flag.Float64Var(&args.Geometry.BaselineOffset, "baseline-offset", svg.DefaultGeometry.BaselineOffset,
+		`Distance in pixels from the vertical center of a cell down to the baseline of its text.`)
+	flag.Float64Var(&args.Geometry.CircleRadius, "circle-radius", svg.DefaultGeometry.CircleRadius,
+		`Radius in pixels of circles.  If 0, a default proportional to -cell-width is used.`)
+	flag.Float64Var(&args.Geometry.ArrowSize, "arrow-size", svg.DefaultGeometry.ArrowSize,
+		`Scale factor for arrowheads.`)
+
+	spanFlag := func(sp *svg.Span, name, usage string) {
+		flag.Func(name, usage,
+			func(s string) (err error) {
+				*sp, err = svg.ParseSpan(s)
+				return
+			})
+	}
+	spanFlag(&args.Rows, "rows",
+		`Draw only the input lines FIRST:LAST, counting from 1 after any -regexp filtering.
+Either number may be omitted e.g. "10:" draws from line 10 to the end.
+Runes at the edges are classified as text or graphics in the context of the full input.`)
+	spanFlag(&args.Cols, "cols",
+		`Draw only the input columns FIRST:LAST, counting from 1, as for -rows.`)
+	flag.BoolVar(&args.Trim, "trim", false,
+		`Remove blank rows and columns from all four sides of the input, after any -rows and -cols.`)
+
+	flag.BoolVar(&args.PerSegment, "per-segment", false,
+		`Draw each line segment and arc as an SVG element of its own, rather than merging
+those that meet end-to-end into continuous <path> elements.`)
+
+	flag.BoolVar(&args.Compact, "compact", false,
+		`Minimize the size of the SVG: define circles and arrowheads once each, as <symbol>
+elements referenced by <use>; drop indentation, empty groups and CSS comments;
+round coordinates.`)
+	flag.BoolVar(&args.Svgz, "svgz", false,
+		`Compress the output with gzip.  Implied by an -o filename ending in .svgz;
+with -io, the output filename ends in .svgz rather than .svg.`)
+	flag.BoolVar(&args.SourceMap, "source-map", false,
+		`Mark each SVG element drawn with attributes data-goat-row and data-goat-col,
+locating the input cell from which it was drawn, counting from 1; and lines
+additionally with data-goat-end-row and data-goat-end-col.`)
+
+	flag.BoolVar(&args.Frame.Tight, "tight", false,
+		`Fit the SVG viewBox to the extents of the graphics and text actually drawn,
+rather than to the rectangle of text cells.`)
+	flag.Func("margin",
+		`Space around the drawing, in pixels of the viewBox: one to four comma-separated
+numbers, in the order of the CSS 'margin' property e.g. "4" or "4,8" or "2,4,6,8".`,
+		func(s string) (err error) {
+			args.Frame.Margin, err = svg.ParseMargins(s)
+			return
+		})
+	flag.Float64Var(&args.Frame.Scale, "scale", 1,
+		`Multiplier applied to the viewBox dimensions, to produce the width and height of the SVG.`)
+	lengthFlag := func(l *svg.Length, name, usage string) {
+		flag.Func(name, usage + `
+Units may be px (the default), mm, cm, in, pt or pc e.g. "120mm".`,
+			func(s string) (err error) {
+				*l, err = svg.ParseLength(s)
+				return
+			})
+	}
+	lengthFlag(&args.Frame.Width, "width",
+		`Width of the SVG.  Unless -height is also given, height follows from the aspect ratio.`)
+	lengthFlag(&args.Frame.Height, "height",
+		`Height of the SVG.  Unless -width is also given, width follows from the aspect ratio.`)
+	lengthFlag(&args.Frame.MaxWidth, "max-width",
+		`Upper limit on the width of the SVG, reduced to which the height follows from the aspect ratio.`)
+
 	flag.StringVar(&args.SvgColorLightScheme, "sls", black, `short for -svg-color-light-scheme`)
 	flag.StringVar(&args.SvgColorLightScheme, "svg-color-light-scheme", black,
 		`See help for -svg-color-dark-scheme`)
@@ -83,6 +259,11 @@ a "light-mode" display, therefore is dark.
 	flag.Usage = func() {
 		clOutput := flag.CommandLine.Output()
 		fmt.Fprintf(clOutput, `Usage: %[1]s [flags] [CSS-filename ...]
+       %[1]s extract [flags] FILE ...
+       %[1]s html [flags] [FILE.html]
+       %[1]s markdown [flags] [FILE.md]
+       %[1]s mdbook
+       %[1]s pandoc-filter [flags] [FORMAT]
 
 %[1]s conforms to the Unix standard for CLI "filter" commands: Read from standard input;
 process the incoming bytes as directed by CLI arguments; write to standard output.
@@ -99,10 +280,38 @@ Input is a UTF-8 encoded byte stream; output is a single <svg> element.
         and appends the element within the output SVG; therefore,
         properties in CSS files later on the command line may override
         those specified by earlier files.
+	Args ending in .txt, directories, and glob patterns e.g. 'docs/*.txt' name
+	diagrams to be drawn as a batch, each to an .svg beside it or under -outdir;
+	or with -lint, to be checked.
 `)
 	}
 	flag.Parse()
 
+	if args.Geometry.CellWidth <= 0 || args.Geometry.CellHeight <= 0 {
+		log.Fatalf("-cell-width and -cell-height must be positive, found %g and %g",
+			args.Geometry.CellWidth, args.Geometry.CellHeight)
+	}
+
+	switch args.Diagnostics {
+	case "text", "json", "sarif":
+	default:
+		log.Fatalf(`-diagnostics must be "text", "json" or "sarif", found %q`, args.Diagnostics)
+	}
+
+	if args.TabStop < 0 {
+		log.Fatalf("-tabstop must not be negative, found %d", args.TabStop)
+	}
+
+	if args.Frame.Scale <= 0 {
+		log.Fatalf("-scale must be positive, found %g", args.Frame.Scale)
+	}
+	flag.Visit(
+		func (fl *flag.Flag) {
+			if fl.Name == "scale" && !(args.Frame.Width.IsZero() && args.Frame.Height.IsZero()) {
+				log.Fatalf("option -scale is incompatible with -width and -height")
+			}
+		})
+
 	if !args.IncludeDefaultCSS {
 		cliColorSettingArgs := map[string]struct{}{
 			"sls": {},
@@ -149,22 +358,51 @@ Input is a UTF-8 encoded byte stream; output is a single <svg> element.
 			//   XX  excessively clever?  More simply, change callee args to []byte ?
 			_, _ = newNR.Seek(0, 0)
 
-			err = svg.ParseCss(markBindingMap, newCss)
+			err = svg.ParseCssFile(markBindingMap, cssFilename, newCss)
 			if err != nil {
-				log.Fatalf(`
-Could not parse file '%s',
-   err = %v`,
-					cssFilename, err)
+				d := svg.AsDiagnostic(err, svg.CodeCSS)
+				d.File = cssFilename
+				reportDiagnostics(&args, []svg.Diagnostic{d})
 			}
 		default:
+			if ext == ".txt" || isGlob(cssFilename) || isDir(cssFilename) {
+				args.inputPaths = append(args.inputPaths, cssFilename)
+				continue
+			}
 			log.Fatalf(`
-Expected filename with suffix .css, found %s with extension %s`,
+Expected filename with suffix .css or .txt, or a directory, found %s with extension %s`,
 				cssFilename, ext)
 		}
 	}
+
+	if len(args.inputPaths) > 0 && !args.Lint {
+		for _, name := range []string{"i", "o", "io"} {
+			if fl := flag.Lookup(name); fl.Value.String() != fl.DefValue {
+				log.Fatalf("option -%s is incompatible with diagrams named as arguments", name)
+			}
+		}
+	}
+	if len(args.OutDir) > 0 && len(args.inputPaths) == 0 {
+		log.Fatalf("option -outdir given, but no diagrams named as arguments")
+	}
+	if args.Jobs < 1 {
+		log.Fatalf("-jobs must be positive, found %d", args.Jobs)
+	}
+	if len(args.Include) == 0 {
+		args.Include = []string{"*.txt"}
+	}
 	return
 }
 
+func isGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+func isDir(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
 func OpenIO(args *Args) (input, output *os.File) {
 	input = os.Stdin
 	if len(args.inputFilename) > 0 {
@@ -179,6 +417,9 @@ func OpenIO(args *Args) (input, output *os.File) {
 			log.Fatalf("options %s and %s are mutually exclusive", "-o", "-io")
 		}
 		output = internal.MustCreate(args.outputFilename)
+		if strings.HasSuffix(args.outputFilename, ".svgz") {
+			args.Svgz = true
+		}
 	}
 	if len(args.ioPathname) > 0 {
 		input = internal.MustOpen(args.ioPathname)
@@ -187,6 +428,9 @@ func OpenIO(args *Args) (input, output *os.File) {
 			log.Fatalf("%s does not end in %s", args.ioPathname, ".txt")
 		}
 		outFilename := before + ".svg"
+		if args.Svgz {
+			outFilename += "z"
+		}
 		output = internal.MustCreate(outFilename)
 	}
 	return
@@ -1,5 +1,8 @@
 /*
    Produce GitHub-Flavored Markdown, for local proofing of README.md
+
+   Fenced code blocks of info string "goat" are drawn as inline SVG; stylesheets they
+   name are sought relative to the current directory.
 */
 package main
 
@@ -22,6 +25,9 @@ import (
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 
+	goatmark "github.com/blampe/goat/goldmark"
+	"github.com/blampe/goat/svg"
+
 	//"github.com/yuin/goldmark/renderer/html"
 
 )
@@ -38,6 +44,12 @@ func main() {
 		),
 		goldmark.WithExtensions(
 			extension.GFM,
+			&goatmark.Extender{
+				Files: os.DirFS("."),
+				Report: func(d svg.Diagnostic) {
+					log.Println(d)
+				},
+			},
 			//&anchor.Extender{},
 		),
 		//goldmark.WithRendererOptions(
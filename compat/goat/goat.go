@@ -0,0 +1,108 @@
+/*
+Package goat provides the entry points of the goat fork vendored by Hugo, on top of packages
+ascii and svg, so that programs written against that API may switch to this module by
+changing only an import path:
+
+	import "github.com/blampe/goat/compat/goat"
+
+	svg := goat.BuildSVG(strings.NewReader(diagram))
+	fmt.Println(svg.Width, svg.Height)
+
+Diagrams are ASCII, drawn with the default stylesheet, black on a light background and white
+on a dark one, as by cmd/goat without options.  Programs wanting more should use svg.Render().
+*/
+package goat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/blampe/goat/ascii"
+	"github.com/blampe/goat/svg"
+)
+
+// A Canvas is an ASCII diagram, ready to be drawn.
+type Canvas struct {
+	config *svg.Config
+	canvas svg.AbstractCanvas
+}
+
+// NewCanvas reads an ASCII diagram from 'in'.
+func NewCanvas(in io.Reader) Canvas {
+	// X  Fails only for want of the stylesheets that are not given.
+	config, err := svg.NewConfig(ascii.ReservedSet, make(svg.MarkBindingMap))
+	if err != nil {
+		panic(err)
+	}
+	return Canvas{
+		config: &config,
+		canvas: ascii.NewCanvas(&config, in),
+	}
+}
+
+// WriteSVG writes the diagram to 'out' as a complete <svg> element.
+func (c *Canvas) WriteSVG(out io.Writer) {
+	svg.WriteCanvas(c.config, c.canvas,
+		true, svg.ColorsOnlyCssFileContent("#000", "#FFF"), nil, out)
+}
+
+// WriteSVGBody writes the content of the <svg> element written by WriteSVG(): the
+// stylesheet and drawing, but not the start and end tags.
+func (c *Canvas) WriteSVGBody(out io.Writer) {
+	var buf bytes.Buffer
+	c.WriteSVG(&buf)
+	_, err := io.WriteString(out, body(buf.String()))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Strip the start and end tags from the <svg> element 's'.
+func body(s string) string {
+	_, s, _ = strings.Cut(s, ">\n")
+	s, _ = strings.CutSuffix(s, svg.CloseSvgElement())
+	return s
+}
+
+// Err returns the problems found in the diagram, as by svg.Config.Err().
+//
+// X  Not of the Hugo-era API, which had no means of reporting them.
+func (c *Canvas) Err() error {
+	return c.config.Err()
+}
+
+// SVG is a diagram drawn by BuildSVG().
+type SVG struct {
+	// Content of the <svg> element, as written by Canvas.WriteSVGBody().
+	Body string
+
+	// In pixels.
+	Width  int
+	Height int
+}
+
+// String returns the complete <svg> element.
+func (s SVG) String() string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1"
+    width="%d" height="%d"
+    viewBox="0 0 %d %d">
+%s</svg>
+`,
+		s.Width, s.Height, s.Width, s.Height, s.Body)
+}
+
+// BuildSVG reads an ASCII diagram from 'src', and draws it.
+func BuildSVG(src io.Reader) SVG {
+	c := NewCanvas(src)
+	var buf bytes.Buffer
+	c.WriteSVG(&buf)
+	v := c.canvas.GetCommon().Viewport(c.config, &svg.Extents{})
+	return SVG{
+		Body:   body(buf.String()),
+		Width:  int(math.Ceil(v.Width.Value)),
+		Height: int(math.Ceil(v.Height.Value)),
+	}
+}
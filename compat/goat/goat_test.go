@@ -0,0 +1,31 @@
+package goat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBuildSVG(t *testing.T) {
+	c := qt.New(t)
+
+	const diagram = "+--+\n|  |-->\n+--+\n"
+	s := BuildSVG(strings.NewReader(diagram))
+	c.Assert(s.Width, qt.Equals, 64)
+	c.Assert(s.Height, qt.Equals, 58)
+	c.Assert(s.Body, qt.Not(qt.Contains), "<svg")
+	c.Assert(s.Body, qt.Contains, "<style")
+
+	// At the default geometry, as would be written by the canvas.
+	canvas := NewCanvas(strings.NewReader(diagram))
+	var out bytes.Buffer
+	canvas.WriteSVG(&out)
+	c.Assert(s.String(), qt.Equals, out.String())
+	c.Assert(canvas.Err(), qt.IsNil)
+
+	out.Reset()
+	canvas.WriteSVGBody(&out)
+	c.Assert(out.String(), qt.Equals, s.Body)
+}
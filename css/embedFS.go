@@ -7,5 +7,5 @@ import (
 // From https://pkg.go.dev/embed
 //   .. The patterns are interpreted relative to the package directory containing the source file ...
 
-//go:embed */*.css
+//go:embed */*.css icons/*.svg
 var FileSystem embed.FS
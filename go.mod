@@ -7,6 +7,8 @@ require (
 	github.com/google/go-cmp v0.6.0
 	github.com/tdewolff/parse/v2 v2.7.19
 	github.com/yuin/goldmark v1.7.13
+	golang.org/x/net v0.47.0
+	golang.org/x/text v0.31.0
 )
 
 require (
@@ -0,0 +1,181 @@
+/*
+Package goldmark extends the Markdown processor goldmark, drawing each fenced code block of
+info string "goat", or "goat-" followed by the name of a dialect, as an inline <svg> element.
+
+	import goatmark "github.com/blampe/goat/goldmark"
+	...
+	md := goldmark.New(
+		goldmark.WithExtensions(&goatmark.Extender{}),
+	)
+
+Options may follow the info string in braces, as for the subcommand 'markdown' of cmd/goat:
+
+	```goat {css=embed:palette/earth.css, dialect=utf8}
+
+Other fenced code blocks are left to the renderer configured otherwise.
+*/
+package goldmark
+
+import (
+	"bytes"
+	"html"
+	"io/fs"
+
+	gm "github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/blampe/goat/internal/fence"
+	"github.com/blampe/goat/svg"
+
+	// Dialects, registered for lookup by name from fences.
+	_ "github.com/blampe/goat/ascii"
+	_ "github.com/blampe/goat/utf8"
+)
+
+// Extender is a goldmark.Extender drawing goat diagrams.  Its zero value draws them as
+// would 'goat -dialect ascii' by default.
+type Extender struct {
+	// Options of all diagrams, except as overridden by those of each fence.
+	// ScopeCSS is always set.
+	RenderOptions svg.RenderOptions
+
+	// File system from which are read the stylesheets named by fences without prefix
+	// "embed:".  If nil, only embedded stylesheets may be named, which is as it should
+	// be for untrusted documents; otherwise os.DirFS() confines names to a directory.
+	Files fs.FS
+
+	// If non-nil, called with each problem found in drawing a diagram, located by line
+	// of the Markdown source; a column counts from the left edge of the diagram.
+	Report func(svg.Diagnostic)
+}
+
+// Extend implements goldmark.Extender.
+func (e *Extender) Extend(m gm.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&transformer{}, 100),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&diagramRenderer{e}, 100),
+	))
+}
+
+// KindDiagram is the ast.NodeKind of a goat diagram.
+var KindDiagram = ast.NewNodeKind("GoatDiagram")
+
+// A Diagram replaces an ast.FencedCodeBlock of goat info string.  Its lines are those
+// of the diagram.
+type Diagram struct {
+	ast.BaseBlock
+
+	// Line of the Markdown source, counting from 1, of the opening fence.
+	Line int
+
+	options fence.Options
+	err     error
+}
+
+// Kind implements ast.Node.
+func (n *Diagram) Kind() ast.NodeKind {
+	return KindDiagram
+}
+
+// IsRaw implements ast.Node.
+func (n *Diagram) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.
+func (n *Diagram) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Dialect": n.options.Dialect,
+	}, nil)
+}
+
+// Replaces each FencedCodeBlock of goat info string by a Diagram.
+type transformer struct{}
+
+func (t *transformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var blocks []*ast.FencedCodeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if b, ok := n.(*ast.FencedCodeBlock); ok && entering && b.Info != nil {
+			blocks = append(blocks, b)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, b := range blocks {
+		opts, ok, err := fence.ParseInfo(string(b.Info.Value(source)))
+		if !ok {
+			continue
+		}
+		d := &Diagram{
+			Line:    1 + bytes.Count(source[:b.Info.Segment.Start], []byte("\n")),
+			options: opts,
+			err:     err,
+		}
+		d.SetLines(b.Lines())
+		b.Parent().ReplaceChild(b.Parent(), b, d)
+	}
+}
+
+type diagramRenderer struct {
+	*Extender
+}
+
+func (r *diagramRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindDiagram, r.renderDiagram)
+}
+
+func (r *diagramRenderer) renderDiagram(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*Diagram)
+	var src bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		src.Write(line.Value(source))
+	}
+
+	var diags []svg.Diagnostic
+	var out bytes.Buffer
+	if n.err != nil {
+		diags = []svg.Diagnostic{{
+			Severity: svg.SeverityError,
+			Code:     fence.CodeOption,
+			Message:  n.err.Error(),
+		}}
+	} else {
+		// Inline, so scoped lest its stylesheets apply to the page.
+		ro := r.RenderOptions
+		ro.ScopeCSS = true
+		diags = fence.Render(&out, src.Bytes(), n.options, ro, r.Files)
+	}
+	if r.Report != nil {
+		for _, d := range diags {
+			// The diagram begins on the line after the fence.
+			if d.Line > 0 {
+				d.Line += n.Line
+			} else {
+				d.Line = n.Line
+			}
+			r.Report(d)
+		}
+	}
+
+	if out.Len() == 0 {
+		// X  Drawn as would be any other code block, so that the source is not lost.
+		_, _ = w.WriteString(`<pre><code class="language-goat">`)
+		_, _ = w.WriteString(html.EscapeString(src.String()))
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkSkipChildren, nil
+	}
+	_, _ = w.Write(out.Bytes())
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,58 @@
+package goldmark
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	gm "github.com/yuin/goldmark"
+
+	"github.com/blampe/goat/svg"
+)
+
+func TestExtender(t *testing.T) {
+	c := qt.New(t)
+
+	var diags []svg.Diagnostic
+	md := gm.New(gm.WithExtensions(&Extender{
+		Report: func(d svg.Diagnostic) {
+			diags = append(diags, d)
+		},
+	}))
+	src := "# Title\n" +
+		"\n" +
+		"```goat-utf8 {css=embed:palette/earth.css}\n" +
+		"──▶\n" +
+		"```\n" +
+		"\n" +
+		"```go\n" +
+		"x := 1\n" +
+		"```\n" +
+		"\n" +
+		"```goat {css=style.css}\n" +
+		"a<b\n" +
+		"```\n" +
+		"\n" +
+		"```goat\n" +
+		"\n" +
+		" a\tb\n" +
+		"```\n"
+	var out bytes.Buffer
+	c.Assert(md.Convert([]byte(src), &out), qt.IsNil)
+
+	html := out.String()
+	c.Assert(bytes.Count(out.Bytes(), []byte("<svg ")), qt.Equals, 2)
+	c.Assert(html, qt.Contains, "palette/earth.css")
+	// Each stylesheet applies only within its own <svg>.
+	c.Assert(strings.Count(html, "@scope {"), qt.Equals, strings.Count(html, "<style "))
+	c.Assert(html, qt.Contains, `<pre><code class="language-go">x := 1`)
+	// Drawn as code, for want of the stylesheet.
+	c.Assert(html, qt.Contains, `<pre><code class="language-goat">a&lt;b`)
+
+	c.Assert(diags, qt.HasLen, 2)
+	c.Assert(diags[0].Line, qt.Equals, 11)
+	c.Assert(diags[0].Message, qt.Matches, `.*only those prefixed "embed:" may be named`)
+	c.Assert(diags[1].Error(), qt.Equals,
+		"17:3: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+}
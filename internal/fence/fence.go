@@ -0,0 +1,217 @@
+// Package fence finds goat diagrams in the fenced code blocks of Markdown, and draws
+// them as directed by the options of their info strings.
+package fence
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blampe/goat/css"
+	"github.com/blampe/goat/svg"
+)
+
+// Prefix of a stylesheet name denoting a file embedded in package css, rather than one
+// of the filesystem.  As for the CSS-filename arguments of cmd/goat.
+const EmbedPrefix = "embed:"
+
+// Code of Diagnostics for unrecognized or invalid options of a fence.
+const CodeOption = "input/fence-option"
+
+// Options of a goat fence, as parsed by ParseInfo().
+type Options struct {
+	// Name of a registered dialect; if empty, that of the caller's choosing.
+	Dialect string
+
+	// Stylesheets, in order: each a path, or EmbedPrefix followed by a name in
+	// css.FileSystem.
+	CSS []string
+
+	// Alternative text of an image drawn from the diagram; may be empty.
+	Alt string
+}
+
+// ParseInfo interprets the info string of a fenced code block.  Unless its first word is
+// "goat", or "goat-" followed by the name of a dialect, 'ok' is false.  Options may
+// follow, enclosed in braces, as KEY=VALUE pairs separated by commas or spaces:
+//
+//	```goat {css=embed:palette/earth.css, dialect=utf8}
+//
+// KEY is one of "css", which may be repeated, "dialect" or "alt".  A VALUE containing
+// commas, spaces or braces must be double-quoted, as a Go string literal.
+func ParseInfo(info string) (opts Options, ok bool, err error) {
+	info = strings.TrimSpace(info)
+	word, rest, _ := strings.Cut(info, " ")
+	word, attrs, braced := strings.Cut(word, "{")
+	if braced {
+		rest = "{" + attrs + " " + rest
+	}
+	switch {
+	case word == "goat":
+	case strings.HasPrefix(word, "goat-"):
+		opts.Dialect = strings.TrimPrefix(word, "goat-")
+	default:
+		return Options{}, false, nil
+	}
+	ok = true
+
+	rest = strings.TrimSpace(rest)
+	if len(rest) > 0 {
+		inner, found := strings.CutPrefix(rest, "{")
+		if found {
+			inner, found = strings.CutSuffix(inner, "}")
+		}
+		if !found {
+			return opts, ok, fmt.Errorf("options %q of fence not enclosed in braces", rest)
+		}
+		pairs, err := splitPairs(inner)
+		if err != nil {
+			return opts, ok, err
+		}
+		for _, pair := range pairs {
+			key, value := pair[0], pair[1]
+			switch key {
+			case "css":
+				opts.CSS = append(opts.CSS, value)
+			case "dialect":
+				opts.Dialect = value
+			case "alt":
+				opts.Alt = value
+			default:
+				return opts, ok, fmt.Errorf(
+					`unknown fence option %q: expected "css", "dialect" or "alt"`, key)
+			}
+		}
+	}
+	if len(opts.Dialect) > 0 {
+		if _, err := svg.LookupDialect(opts.Dialect); err != nil {
+			return opts, ok, err
+		}
+	}
+	return
+}
+
+// Split 's' into KEY=VALUE pairs, unquoting any quoted VALUE.
+func splitPairs(s string) (pairs [][2]string, err error) {
+	isSeparator := func(c byte) bool {
+		return c == ',' || c == ' ' || c == '\t'
+	}
+	for i := 0; i < len(s); {
+		if isSeparator(s[i]) {
+			i++
+			continue
+		}
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("fence option %q lacks '=VALUE'", s[i:])
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		var value string
+		if i < len(s) && s[i] == '"' {
+			quoted, err := strconv.QuotedPrefix(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("fence option %q: %w", key, err)
+			}
+			value, _ = strconv.Unquote(quoted)
+			i += len(quoted)
+		} else {
+			end := i
+			for end < len(s) && !isSeparator(s[end]) {
+				end++
+			}
+			value = s[i:end]
+			i = end
+		}
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return
+}
+
+// LoadCSS reads the stylesheets 'names' from 'fsys', or if prefixed by EmbedPrefix, from
+// css.FileSystem.  If 'fsys' is nil, only the latter are allowed.
+// Each file read from an UnconfinedDir() is named by its path, against whose directory
+// its goat-glyph-src is resolved; others keep the name given.
+func LoadCSS(names []string, fsys fs.FS) (files []svg.CSSFile, err error) {
+	for _, name := range names {
+		var content []byte
+		if after, found := strings.CutPrefix(name, EmbedPrefix); found {
+			content, err = css.FileSystem.ReadFile(after)
+		} else if fsys == nil {
+			err = fmt.Errorf("stylesheet %q: only those prefixed %q may be named", name, EmbedPrefix)
+		} else {
+			content, err = fs.ReadFile(fsys, name)
+			if d, ok := fsys.(unconfinedDir); ok {
+				name = d.path(name)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, svg.CSSFile{Name: name, Content: content})
+	}
+	return
+}
+
+// UnconfinedDir returns a file system reading paths relative to 'dir', or absolute.
+// Unlike that of os.DirFS(), it does not confine them to 'dir', so is fit only for
+// trusted documents.
+func UnconfinedDir(dir string) fs.FS {
+	return unconfinedDir(dir)
+}
+
+type unconfinedDir string
+
+func (d unconfinedDir) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(string(d), name)
+}
+
+func (d unconfinedDir) Open(name string) (fs.File, error) {
+	return os.Open(d.path(name))
+}
+
+// ReadFile is called by fs.ReadFile(), bypassing its check of fs.ValidPath().
+func (d unconfinedDir) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(d.path(name))
+}
+
+// Render draws the diagram 'src' of a fence carrying 'opts' to 'out', as a complete
+// <svg> element, with the options of 'base' except as overridden by 'opts'.
+// Stylesheets of 'opts' are read by LoadCSS() from 'fsys'.
+//
+// All Diagnostics found are returned, whatever their severity, located relative to the
+// first line of 'src'.  Unless one is of class "css" or "io", an SVG has been written.
+func Render(out io.Writer, src []byte, opts Options, base svg.RenderOptions, fsys fs.FS) []svg.Diagnostic {
+	cssFiles, err := LoadCSS(opts.CSS, fsys)
+	if err != nil {
+		return []svg.Diagnostic{svg.AsDiagnostic(err, svg.CodeIO)}
+	}
+	ro := base
+	if len(opts.Dialect) > 0 {
+		ro.Dialect = opts.Dialect
+	}
+	ro.CSS = append(append([]svg.CSSFile(nil), base.CSS...), cssFiles...)
+
+	// X  Render() returns only the errors; the warnings are wanted too.
+	var config *svg.Config
+	configure := base.Configure
+	ro.Configure = func(c *svg.Config) {
+		config = c
+		if configure != nil {
+			configure(c)
+		}
+	}
+	err = svg.Render(out, bytes.NewReader(src), ro)
+	if config == nil {
+		return []svg.Diagnostic{svg.AsDiagnostic(err, svg.CodeCSS)}
+	}
+	return config.Diagnostics
+}
@@ -0,0 +1,98 @@
+package fence
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/blampe/goat/svg"
+
+	_ "github.com/blampe/goat/ascii"
+	_ "github.com/blampe/goat/utf8"
+)
+
+func TestParseInfo(t *testing.T) {
+	c := qt.New(t)
+
+	opts, ok, err := ParseInfo(`goat {css=embed:palette/earth.css, dialect=utf8 alt="A, B"}`)
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(opts, qt.DeepEquals, Options{
+		Dialect: "utf8",
+		CSS:     []string{"embed:palette/earth.css"},
+		Alt:     "A, B",
+	})
+
+	opts, ok, err = ParseInfo("goat-utf8{css=a.css css=b.css}")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(opts.Dialect, qt.Equals, "utf8")
+	c.Assert(opts.CSS, qt.DeepEquals, []string{"a.css", "b.css"})
+
+	_, ok, _ = ParseInfo("go")
+	c.Assert(ok, qt.IsFalse)
+
+	_, ok, err = ParseInfo("goat {color=red}")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, `unknown fence option "color".*`)
+	_, _, err = ParseInfo("goat-klingon")
+	c.Assert(err, qt.ErrorMatches, `unknown dialect "klingon".*`)
+	_, _, err = ParseInfo("goat css=a.css")
+	c.Assert(err, qt.ErrorMatches, `options .* not enclosed in braces`)
+}
+
+func TestBlocks(t *testing.T) {
+	c := qt.New(t)
+
+	src := strings.Join([]string{
+		"text",
+		"```goat",
+		"+--+",
+		"```",
+		"  ~~~~ goat-utf8",
+		"   ┌┐",
+		"  ```",
+		"  ~~~~",
+		"```",
+		"unclosed",
+	}, "\n")
+	blocks := Blocks([]byte(src))
+	c.Assert(blocks, qt.HasLen, 3)
+
+	c.Assert(blocks[0].Info, qt.Equals, "goat")
+	c.Assert(blocks[0].Line, qt.Equals, 3)
+	c.Assert(string(blocks[0].Content), qt.Equals, "+--+\n")
+	c.Assert(src[blocks[0].Start:blocks[0].End], qt.Equals, "```goat\n+--+\n```\n")
+
+	c.Assert(blocks[1].Info, qt.Equals, "goat-utf8")
+	c.Assert(blocks[1].Indent, qt.Equals, 2)
+	c.Assert(string(blocks[1].Content), qt.Equals, " ┌┐\n```\n")
+
+	c.Assert(string(blocks[2].Content), qt.Equals, "unclosed")
+	c.Assert(blocks[2].End, qt.Equals, len(src))
+}
+
+func TestRender(t *testing.T) {
+	c := qt.New(t)
+
+	opts, _, _ := ParseInfo("goat {css=embed:palette/earth.css}")
+	var out bytes.Buffer
+	diags := Render(&out, []byte("a\tb\n"), opts, svg.RenderOptions{}, nil)
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Line, qt.Equals, 1)
+
+	out.Reset()
+	diags = Render(&out, []byte("--->\n"), opts, svg.RenderOptions{}, nil)
+	c.Assert(diags, qt.HasLen, 0)
+	c.Assert(out.String(), qt.Contains, "palette/earth.css")
+
+	opts.CSS = []string{"no-such.css"}
+	diags = Render(&out, []byte("--->\n"), opts, svg.RenderOptions{}, nil)
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Message, qt.Matches, `.* only those prefixed "embed:" may be named`)
+	diags = Render(&out, []byte("--->\n"), opts, svg.RenderOptions{}, UnconfinedDir("."))
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Class(), qt.Equals, "io")
+}
@@ -0,0 +1,99 @@
+package fence
+
+import (
+	"bytes"
+)
+
+// A Block is a fenced code block of a Markdown document.
+type Block struct {
+	// Byte offsets within the document of the opening fence line, and just past the
+	// closing fence line, or the end of the document if the block is never closed.
+	Start, End int
+
+	// Line of the document, counting from 1, of the first line of 'Content'.
+	Line int
+
+	// Indentation of the opening fence, in spaces.
+	Indent int
+
+	Info string
+
+	// Lines between the fences, each with its "\n" and stripped of up to 'Indent'
+	// leading spaces.
+	Content []byte
+}
+
+// Blocks finds the fenced code blocks of the Markdown document 'src', as recognized
+// by CommonMark: an opening line of at least three '`' or '~' characters, indented by
+// at most three spaces, up to a closing line of at least as many of the same.
+//
+//	https://spec.commonmark.org/0.31.2/#fenced-code-blocks
+//
+// X  Fences within block quotes are not recognized; nor are lines of indented code
+//    blocks told from fences.
+func Blocks(src []byte) (blocks []Block) {
+	var (
+		open    *Block
+		fenceCh byte
+		fenceN  int
+	)
+	lineNumber := 0
+	for pos := 0; pos < len(src); {
+		end := bytes.IndexByte(src[pos:], '\n') + 1
+		if end == 0 {
+			end = len(src) - pos
+		}
+		end += pos
+		line := src[pos:end]
+		lineNumber++
+
+		indent, ch, n, rest := fenceLine(line)
+		switch {
+		case open == nil:
+			if n >= 3 && indent <= 3 && !(ch == '`' && bytes.IndexByte(rest, '`') >= 0) {
+				open = &Block{
+					Start:  pos,
+					Line:   lineNumber + 1,
+					Indent: indent,
+					Info:   string(bytes.TrimSpace(rest)),
+				}
+				fenceCh, fenceN = ch, n
+			}
+		case ch == fenceCh && n >= fenceN && indent <= 3 && len(bytes.TrimSpace(rest)) == 0:
+			open.End = end
+			blocks = append(blocks, *open)
+			open = nil
+		default:
+			strip := 0
+			for strip < open.Indent && strip < len(line) && line[strip] == ' ' {
+				strip++
+			}
+			open.Content = append(open.Content, line[strip:]...)
+		}
+		pos = end
+	}
+	if open != nil {
+		open.End = len(src)
+		blocks = append(blocks, *open)
+	}
+	return
+}
+
+// Split 'line' into its indentation, the character and length of the run of '`' or '~'
+// following, and what remains.  'n' is 0 unless the run is of at least three.
+func fenceLine(line []byte) (indent int, ch byte, n int, rest []byte) {
+	for indent < len(line) && line[indent] == ' ' {
+		indent++
+	}
+	if indent == len(line) || (line[indent] != '`' && line[indent] != '~') {
+		return indent, 0, 0, nil
+	}
+	ch = line[indent]
+	for indent+n < len(line) && line[indent+n] == ch {
+		n++
+	}
+	if n < 3 {
+		return indent, 0, 0, nil
+	}
+	return indent, ch, n, line[indent+n:]
+}
@@ -0,0 +1,46 @@
+package testlib
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blampe/goat/svg"
+)
+
+// BenchmarkExamples times parsing and SVG output of each examples/*.txt file,
+// as a sub-benchmark named by the file.
+//
+// X  Files are read into memory beforehand, so that only GoAT's own work is timed.
+func BenchmarkExamples(b *testing.B, newCanvas svg.NewCanvasFunc) {
+	txtPaths, err := filepath.Glob(filepath.Join(ExamplesDir, "*.txt"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, path := range txtPaths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".txt")
+		b.Run(name, func(b *testing.B) {
+			BenchmarkSource(b, src, newCanvas)
+		})
+	}
+}
+
+// BenchmarkSource times parsing and SVG output of the diagram 'src'.
+func BenchmarkSource(b *testing.B, src []byte, newCanvas svg.NewCanvasFunc) {
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		config := svg.Config{
+			LineFilter: noHashCommentLine_re,
+		}
+		ac := newCanvas(&config, bytes.NewReader(src))
+		WriteCanvasNoCssFiles(&config, ac, "", io.Discard)
+	}
+}
@@ -46,12 +46,18 @@ var (
 newly-generated SVG that does not match those in ` + ExamplesDir)
 )
 
-// XX  promote to goat/ ?
-type newCanvasFunc func(*svg.Config, io.Reader) svg.AbstractCanvas
+// RegressionDialect runs Regression() for the dialect registered as 'name'.
+func RegressionDialect(t *testing.T, name string) {
+	d, err := svg.LookupDialect(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Regression(t, d.ReservedSet, d.NewCanvas)
+}
 
 func Regression(t *testing.T,
 	reservedSet goat.RuneSet,
-	newCanvas newCanvasFunc) {
+	newCanvas svg.NewCanvasFunc) {
 
 	// XX  This sweeps up ~every~ *.txt file in examples/
 	txtPaths, err := filepath.Glob(filepath.Join(ExamplesDir, "*.txt"))
@@ -102,6 +108,9 @@ func Regression(t *testing.T,
 	}
 	config, err := svg.NewConfig(reservedSet, markBindingMap)
 	config.LineFilter = noHashCommentLine_re
+	// X  The reference SVGs show each line and arc as an element of its own, the
+	//    better to locate a difference.
+	config.MergeStrokes = false
 	if err != nil {
 		t.Fatalf(`
     Aborting: "%v"`, err)
@@ -119,7 +128,7 @@ func Regression(t *testing.T,
 var noHashCommentLine_re = regexp.MustCompile(`^(?:[^#].*)?$`)
 
 func writeExamples(t *testing.T,
-	config *svg.Config, newCanvas newCanvasFunc, cssBytes string,
+	config *svg.Config, newCanvas svg.NewCanvasFunc, cssBytes string,
 	inDir, outDir string, baseNames []string) {
 
 	for _, name := range baseNames {
@@ -139,7 +148,7 @@ func mustCreate(txtFilename string) *os.File {
 }
 
 func verifyExamples(t *testing.T,
-	config *svg.Config, newCanvas newCanvasFunc, cssBytes string,
+	config *svg.Config, newCanvas svg.NewCanvasFunc, cssBytes string,
 	ExamplesDir string, baseNames []string) {
 
 	var failures []string
@@ -150,6 +159,11 @@ func verifyExamples(t *testing.T,
 		buff := &bytes.Buffer{}
 		WriteCanvasNoCssFiles(config, ac, cssBytes, buff)
 		in.Close()
+		// X  The examples are expected to be free of errors, e.g. unclosed marks.
+		if err := config.Err(); err != nil {
+			t.Errorf("%s: %v", name, err)
+			config.Diagnostics = nil
+		}
 		if nil != CompareSVG(t, buff, ExamplesDir, name) {
 			failures = append(failures, name)
 		}
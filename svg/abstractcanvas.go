@@ -3,13 +3,15 @@ package svg
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"log" // Tests may want to suppress log output.
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/blampe/goat/internal"
 )
@@ -31,8 +33,27 @@ type CanvasCommon struct {
 	// units of cells
 	Width, Height int
 
-	Data        map[XyIndex]rune
-	TextRunes   map[XyIndex]rune
+	// Width*Height runes in row-major order, of which those at offsets set in
+	// 'isText' are text, the remainder possibly graphics.  See grid.go.
+	cells  []rune
+	isText bitmap
+
+	// Cells holding user-defined joints, seen by RuneAt() as 'jointAs'; nil if none.
+	// See MarkJoints().
+	isJoint bitmap
+	jointAs rune
+
+	// Grapheme clusters of more than one rune, by offset in 'cells' of the cell
+	// holding the first.  Nil if none.
+	clusters map[int]string
+
+	// Position within the input diagram of cell {0,0}, non-zero after cropping.
+	Offset XyIndex
+
+	// For Diagnostics: name of the file read, if known, and the line number
+	// within it of each row of the input diagram, before cropping.
+	file        string
+	lineNumbers []int
 }
 
 // 'ac.CanvasCommon().text' will contain begin and end marks for text styling
@@ -44,17 +65,41 @@ func WriteCanvas(config *Config, ac AbstractCanvas,
 	cssInclude []internal.NamedReadSeeker,    // XX pass 'defaultCSS' in this way -- from ALL callers?   
 	dst io.Writer) {
 
+	// X  'dst' is commonly an unbuffered *os.File.
+	out := bufio.NewWriter(dst)
 	mustPrintS := func(s string) {
-		internal.MustFPrintf(dst, `%s`, s)
+		_, err := out.WriteString(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// The body is drawn first, because the size of the enclosing <svg> element
+	// may depend on the extents of what is drawn.
+	body := &drawing{
+		mergeStrokes: config.MergeStrokes,
+		compact: config.Compact,
+		sourceMap: config.SourceMap,
+		source: ac.GetCommon(),
+	}
+	ac.WriteSVGBody(body, config)
+	viewport := ac.GetCommon().Viewport(config, &body.Extents)
+	viewport.XLink = body.xlink
+
+	mustPrintS(viewport.OpenSvgElement())
+
+	printStyle := func(sourceOrigin, css string) {
+		style := newStyleElement(sourceOrigin, scopedCSS(config, css))
+		if config.Compact {
+			style = compactCSS(style)
+		}
+		mustPrintS(style)
 	}
-	mustPrintS(ac.GetCommon().OpenSvgElement())
 
 	// Include this first, so individual properties can be overridden.
 	if includeDefaultCSS {
-		mustPrintS(
-			newStyleElement(
-				"source-independent defaults: shared by ASCII and UTF-8",
-				defaultCSS + colorsOnlyBytes))
+		printStyle("source-independent defaults: shared by ASCII and UTF-8",
+			defaultCSS + colorsOnlyBytes)
 	}
 
 	for _, cssR := range cssInclude {
@@ -66,34 +111,59 @@ func WriteCanvas(config *Config, ac AbstractCanvas,
 			err = fmt.Errorf("Error in %s: '%v'", title, err)
 			log.Fatal(err)
 		}
-		mustPrintS(
-			newStyleElement(title, string(bs)))
+		printStyle(title, string(bs))
 	}
 
-	mustPrintS(OpenGElement())
+	mustPrintS(viewport.OpenGElement())
 
-	ac.WriteSVGBody(dst, config)
+	var prefix bytes.Buffer
+	body.symbols.writeDefs(&prefix)
+	// Painted first, as would be the individual lines and arcs they replace.
+	if config.MergeStrokes {
+		writeMergedStrokes(&prefix, body)
+	}
+	inner := append(prefix.Bytes(), body.Bytes()...)
+	if config.Compact {
+		inner = compactBody(inner)
+	}
+	inner = uniqueIDs(inner)
+	_, err := out.Write(inner)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	mustPrintS(CloseGElement())
 	mustPrintS(CloseSvgElement())
+	if err := out.Flush(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 // text returns a slice of all text characters not belonging to part of the diagram.
 // Must be stably sorted, to satisfy regression tests.
 func (c *CanvasCommon) text() (textRunes []text) {
 	for idx := range LeftRightMinor(c.Width, c.Height) {
-		r, found := c.TextRunes[idx]
+		r, found := c.TextRuneAt(idx)
 		if !found {
 			continue
 		}
 		if r == 0 {
 			log.Fatalf("found rune with value 0x%x", r)
 		}
-		textRunes = append(textRunes,
-			text{
-				Start: idx,
-				r: r,
-			})
+		if r == wideTail {
+			continue
+		}
+		t := text{
+			Start: idx,
+			r: r,
+			cells: 1,
+		}
+		n, _ := c.offsetOf(idx)
+		t.cluster = c.clusters[n]
+		if idx.X+1 < c.Width && c.cells[n+1] == wideTail {
+			t.cells = 2
+		}
+		textRunes = append(textRunes, t)
 	}
 	return
 }
@@ -104,17 +174,22 @@ func (c *CanvasCommon) text() (textRunes []text) {
 type text struct {
 	Start	 XyIndex    // XX  non-intuitive name: start and end are the same cell
 	r rune
+
+	// The whole grapheme cluster beginning with 'r', if of more than one rune.
+	cluster string
+
+	// Cells covered: 2 for a wide character, otherwise 1.
+	cells int
 }
 
 func (t text) String() string {
 	return fmt.Sprintf(`xy index: %#v, character: %q`, t.Start, string(t.r))
 }
 
-// Looks only at c.Data[], ignores c.TextRunes[].
-// Returns the rune for ASCII Space i.e. ' ', in the event that map lookup fails.
-//  XX  Name 'dataRuneAt()' would be more descriptive, but maybe too bulky.
+// Ignores runes classified as text.
+// Returns the rune for ASCII Space i.e. ' ', in the event that lookup fails.
 func (c *CanvasCommon) RuneAt(i XyIndex) rune {
-	if val, ok := c.Data[i]; ok {
+	if val, ok := c.DataRuneAt(i); ok {
 		return val
 	}
 	return ' '
@@ -122,45 +197,54 @@ func (c *CanvasCommon) RuneAt(i XyIndex) rune {
 
 // NewCanvasCommon creates a fully-populated CanvasCommon according to GoAT-formatted text read from
 // an io.Reader, consuming all bytes available.
+//
+// A leading byte order mark is dropped, and lines may end in "\r\n", "\n" or "\r".
+//
+// X  If the input cannot be read, or contains a TAB while config.TabStop is 0, the
+//    canvas returned is empty, and the problem is recorded in config.Diagnostics.
 func NewCanvasCommon(config *Config, in io.Reader) (c CanvasCommon) {
 	scanner := bufio.NewScanner(in)
-	split := bufio.ScanLines
-	if config.LineFilter != nil {
-		split = func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-			advance, token, err = bufio.ScanLines(data, atEOF)
-			if token == nil || err != nil || atEOF {
-				return
-			}
-			if ! config.LineFilter.Match(token) {
-				token = nil
-			}
+	scanner.Buffer(nil, maxLineLength)
+	var (
+		lineNumbers []int
+		lineNumber int
+	)
+	split := func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		advance, token, err = scanLines(data, atEOF)
+		if token == nil || err != nil {
+			return
+		}
+		lineNumber++
+		if lineNumber == 1 {
+			token = bytes.TrimPrefix(token, byteOrderMark)
+		}
+		if config.LineFilter != nil && !atEOF && !config.LineFilter.Match(token) {
+			token = nil
 			return
 		}
+		lineNumbers = append(lineNumbers, lineNumber)
+		return
 	}
 	// Set the split function for the scanning operation.
 	scanner.Split(split)
 
-	c, err := newCanvasCommon(scanner)
+	c, notes, err := newCanvasCommon(scanner, config)
+	c.file = filenameFromReader(in)
+	c.lineNumbers = lineNumbers
 	if err != nil {
-		fatalIoReaderError(err, in)
+		notes = append(notes, AsDiagnostic(err, CodeIO))
+	}
+	// X  Diagnostics from newCanvasCommon() bear the row of the diagram as 'Line'.
+	for _, d := range notes {
+		d.File = c.file
+		if d.Line > 0 && d.Line <= len(lineNumbers) {
+			d.Line = lineNumbers[d.Line-1]
+		}
+		config.Diagnostics = append(config.Diagnostics, d)
 	}
-
-	// XX ? Separate and promote to caller this phase of processing and
-	//      the particular data structure it creates?
-	c.TextRunes = make(map[XyIndex]rune)
 	return
 }
 
-// XX  Refactor?  Move to files.go -- more general package?
-func fatalIoReaderError(err error, in io.Reader) {
-	fileName := filenameFromReader(in)
-	if len(fileName) == 0 {
-		fileName = "input diagram not read from a named file"
-	}
-	err = fmt.Errorf("Error in %s: '%v'",
-		fileName, err)
-	log.Fatal(err)
-}
 func filenameFromReader(in io.Reader) string {
 	file, isFile := in.(*os.File)
 	if isFile {
@@ -169,65 +253,192 @@ func filenameFromReader(in io.Reader) string {
 	return ""
 }
 
-// Create and populate a 'data' map.
-func newCanvasCommon(scanner *bufio.Scanner) (CanvasCommon, error) {
-	data := make(map[XyIndex]rune)
+// Create and populate the 'cells' slice, expanding TABs to the next multiple of
+// config.TabStop if non-zero, with a note of each, and normalizing each line to NFC
+// if config.NFC.  Invalid UTF-8 is drawn as U+FFFD, with a warning.
+//
+// Each grapheme cluster occupies the cell of its first rune, and if that is a wide
+// character, the cell following too, filled with 'wideTail'.  Clusters of several
+// runes are recorded whole in 'clusters'.
+func newCanvasCommon(scanner *bufio.Scanner, config *Config) (_ CanvasCommon, notes []Diagnostic, _ error) {
+	tabStop := config.TabStop
+	// The runes of all lines, each beginning at the offset given in 'lineStarts'.
+	// X  Kept thus in one slice, and the lines read by Bytes() rather than Text(),
+	//    so that reading allocates little more than 'cells' itself.
+	var line []rune
+	var lineStarts []int
+	type clusterAt struct {
+		x, y int
+		s string
+	}
+	var clusterList []clusterAt
 	width := 0
 	height := 0
 
 	for scanner.Scan() {
-		lineStr := scanner.Text()
+		lineBytes := scanner.Bytes()
+		if config.NFC {
+			lineBytes = norm.NFC.Bytes(lineBytes)
+		}
 
+		start := len(line)
+		lineStarts = append(lineStarts, start)
 		w := 0
+		// Runes of the input consumed, as distinct from the cells 'w' when TABs are expanded.
+		col := 0
+
+		// The cluster being scanned: index in 'line' of its first rune, and its runes.
+		base := -1
+		var cluster []rune
+		endCluster := func() {
+			if len(cluster) > 1 {
+				clusterList = append(clusterList, clusterAt{base, height, string(cluster)})
+			}
+			base, cluster = -1, cluster[:0]
+		}
 		// X  Type of second value assigned from "for ... range" operator over a string is "rune".
 		//               https://go.dev/ref/spec#For_statements
 		//    But yet, counterintuitively, type of lineStr[_index_] is 'byte'.
 		//               https://go.dev/ref/spec#String_types
-		for _, r := range lineStr {
+		for i, r := range string(lineBytes) {
 			//if r > 255 {
 			//	fmt.Printf("linestr=\"%s\"\n", lineStr)
 			//	fmt.Printf("r == 0x%x\n", r)
 			//}
+			col++
+			if r == utf8.RuneError {
+				if _, size := utf8.DecodeRune(lineBytes[i:]); size == 1 {
+					notes = append(notes, Diagnostic{
+						Line:     height+1,
+						Col:      col,
+						Severity: SeverityWarning,
+						Code:     CodeInvalidUTF8,
+						Message:  fmt.Sprintf("invalid UTF-8 byte 0x%02x, drawn as U+FFFD", lineBytes[i]),
+					})
+				}
+			}
 			if r == '	' {
-				return CanvasCommon{}, fmt.Errorf("Found TAB at row %d, column %d",
-					height+1, w)
+				if tabStop > 0 {
+					notes = append(notes, Diagnostic{
+						Line:     height+1,
+						Col:      col,
+						Severity: SeverityNote,
+						Code:     CodeTabExpanded,
+						Message:  fmt.Sprintf("TAB expanded to %d spaces", tabStop - w%tabStop),
+					})
+					endCluster()
+					for {
+						line = append(line, ' ')
+						w++
+						if w%tabStop == 0 {
+							break
+						}
+					}
+					continue
+				}
+				// X  Line is the row of the diagram, mapped by the caller to that of the input.
+				return CanvasCommon{}, notes, Diagnostic{
+					Line:     height+1,
+					Col:      col,
+					Severity: SeverityError,
+					Code:     CodeTab,
+					Message:  "found TAB: expand tabs to spaces, or give a tab stop",
+				}
+			}
+			if base >= 0 {
+				last := cluster[len(cluster)-1]
+				// X  Regional indicators pair off as flags.
+				pairsFlag := len(cluster) == 1 &&
+					isRegionalIndicator(last) && isRegionalIndicator(r)
+				if extendsCluster(last, r) || pairsFlag {
+					cluster = append(cluster, r)
+					continue
+				}
 			}
-			i := XyIndex{w, height}
-			data[i] = r
+			endCluster()
+			base, cluster = len(line) - start, append(cluster, r)
+			line = append(line, r)
 			w++
+			if runeWidth(r) == 2 {
+				line = append(line, wideTail)
+				w++
+			}
 		}
+		endCluster()
 
 		if w > width {
 			width = w
 		}
 		height++
 	}
+	if err := scanner.Err(); err != nil {
+		return CanvasCommon{}, notes, Diagnostic{
+			Severity: SeverityError,
+			Code:     CodeIO,
+			Message:  err.Error(),
+		}
+	}
 	if height == 0 {
 		// Return an error, for fuller error diagnostics to CLI user.
-		return CanvasCommon{}, errors.New("input appears to be empty!")
+		return CanvasCommon{}, notes, Diagnostic{
+			Severity: SeverityError,
+			Code:     CodeEmptyInput,
+			Message:  "input appears to be empty!",
+		}
+	}
+	// X  Lines shorter than 'width' are padded with 'absent', as distinct from ' '.
+	cells := make([]rune, width*height)
+	lineStarts = append(lineStarts, len(line))
+	for y := range height {
+		copy(cells[y*width:], line[lineStarts[y]:lineStarts[y+1]])
+	}
+	var clusters map[int]string
+	if len(clusterList) > 0 {
+		clusters = make(map[int]string, len(clusterList))
+		for _, c := range clusterList {
+			clusters[c.y*width+c.x] = c.s
+		}
 	}
 	return CanvasCommon{
-		Data: data,
+		cells: cells,
+		clusters: clusters,
 		Width: width,
 		Height: height,
-		TextRunes: nil,  // XX
-	}, nil
+	}, notes, nil
 }
 
-// Move contents of every cell that appears, according to a tricky set of rules,
-// to be "text", into a separate map: from data[] to textRunes[].
-// So data[] and textRunes[] are an exact partitioning of the
+// ReadCanvas reads a diagram from 'in' into the CanvasCommon of 'ac', a dialect's
+// canvas otherwise constructed; then applies to it, in order, the steps every dialect
+// needs: MarkJoints() of config.RuneRoles.JointRunes, drawn as would be 'joint';
+// MoveToText(); and CropAndTrim().  A NewCanvasFunc may end by returning its result:
+//
+//	c := &Canvas{...}
+//	return svg.ReadCanvas(config, in, c, '+')
+func ReadCanvas(config *Config, in io.Reader, ac AbstractCanvas, joint rune) AbstractCanvas {
+	cc := ac.GetCommon()
+	*cc = NewCanvasCommon(config, in)
+	cc.MarkJoints(config.RuneRoles.JointRunes, joint)
+	// Mark the cells to be drawn as text, according to ac.ShouldMoveToTextRunes()
+	MoveToText(ac)
+	CropAndTrim(config, ac)
+	return ac
+}
+
+// Mark every cell that appears, according to a tricky set of rules, to be "text".
+// Thereafter RuneAt() and TextRuneAt() see an exact partitioning of the
 // incoming grid-aligned runes.
+//
+// X  Classification of every cell sees all others as not yet marked.
 func MoveToText(ac AbstractCanvas) {
 	cc := ac.GetCommon()
+	isText := newBitmap(len(cc.cells))
 	for i := range LeftRightMinor(cc.Width, cc.Height) {
 		if ac.ShouldMoveToTextRunes(i) {
-			cc.TextRunes[i] = cc.RuneAt(i)	// cc.RuneAt() Reads from cc.Data[]
+			n, _ := cc.offsetOf(i)
+			isText.set(n)
 		}
 	}
-	for i := range cc.TextRunes {
-		delete(cc.Data, i)
-	}
+	cc.isText = isText
 }
 
 func CanvasString(ac AbstractCanvas) string {
@@ -236,18 +447,16 @@ func CanvasString(ac AbstractCanvas) string {
 
 	for h := 0; h < cc.Height; h++ {
 		for w := 0; w < cc.Width; w++ {
-			idx := XyIndex{w, h}
-
-			// Search 'text' map; if nothing there try the 'data' map.
-			r, ok := cc.TextRunes[idx]
-			if !ok {
-				r = cc.RuneAt(idx)
+			// X  The second cell of a wide character adds nothing to the first.
+			r := cc.runeAnywhereAt(XyIndex{w, h})
+			if r == wideTail {
+				continue
 			}
-
-			_, err := buffer.WriteRune(r)
-			if err != nil {
+			if s, found := cc.clusters[h*cc.Width+w]; found {
+				buffer.WriteString(s)
 				continue
 			}
+			buffer.WriteRune(r)
 		}
 
 		err := buffer.WriteByte('\n')
@@ -0,0 +1,61 @@
+package svg
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/blampe/goat"
+)
+
+func TestTabs(t *testing.T) {
+	c := qt.New(t)
+
+	// Lines discarded by the filter are counted.
+	config := Config{LineFilter: regexp.MustCompile(`^[^#]`)}
+	cc := NewCanvasCommon(&config, strings.NewReader("# comment\nab\tc\n"))
+	c.Assert(cc.Width, qt.Equals, 0)
+	c.Assert(config.Diagnostics, qt.HasLen, 1)
+	c.Assert(config.Diagnostics[0].Error(), qt.Equals,
+		"2:3: error: found TAB: expand tabs to spaces, or give a tab stop [input/tab]")
+	c.Assert(config.Diagnostics[0].Class(), qt.Equals, "input")
+
+	// With a tab stop, TABs are expanded, each with a note.
+	config = Config{TabStop: 4}
+	cc = NewCanvasCommon(&config, strings.NewReader("ab\tc\n\t\td\n"))
+	c.Assert(cc.Width, qt.Equals, 9)
+	r, _ := cc.DataRuneAt(XyIndex{4, 0})
+	c.Assert(r, qt.Equals, 'c')
+	r, _ = cc.DataRuneAt(XyIndex{8, 1})
+	c.Assert(r, qt.Equals, 'd')
+	c.Assert(config.Err(), qt.IsNil)
+	c.Assert(config.Diagnostics, qt.HasLen, 3)
+	c.Assert(config.Diagnostics[2].Error(), qt.Equals,
+		"2:2: note: TAB expanded to 4 spaces [input/tab-expanded]")
+}
+
+// Treats no rune as text.
+type noText struct {
+	CanvasCommon
+}
+
+func (c *noText) GetCommon() *CanvasCommon           { return &c.CanvasCommon }
+func (c *noText) WriteSVGBody(io.Writer, *Config)     {}
+func (c *noText) ShouldMoveToTextRunes(XyIndex) bool { return false }
+
+func TestReadCanvas(t *testing.T) {
+	c := qt.New(t)
+
+	config := Config{Rows: Span{2, 2}}
+	config.RuneRoles.JointRunes = goat.MakeRuneSet('#')
+	ac := ReadCanvas(&config, strings.NewReader("ab\n#c\n"), &noText{}, '+')
+	cc := ac.GetCommon()
+	c.Assert(cc.Width, qt.Equals, 2)
+	c.Assert(cc.Height, qt.Equals, 1)
+	r, _ := cc.DataRuneAt(XyIndex{0, 0})
+	c.Assert(r, qt.Equals, '+')
+	c.Assert(cc.WrittenRuneAt(XyIndex{0, 0}), qt.Equals, '#')
+}
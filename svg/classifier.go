@@ -0,0 +1,41 @@
+package svg
+
+import (
+	"fmt"
+)
+
+// Classifier selects the rules by which a dialect tells text from graphics,
+// among runes of its ReservedSet.
+type Classifier int
+
+const (
+	// Decide from the runes immediately neighboring each cell.
+	ClassifierClassic Classifier = iota
+
+	// Additionally consider the whole whitespace-delimited token containing each
+	// cell: a token containing letters or digits is text, except where a line
+	// clearly attaches to it.
+	ClassifierWords
+)
+
+var classifierNames = []string{
+	ClassifierClassic: "classic",
+	ClassifierWords:   "words",
+}
+
+func (cl Classifier) String() string {
+	if int(cl) < len(classifierNames) {
+		return classifierNames[cl]
+	}
+	return fmt.Sprintf("Classifier(%d)", int(cl))
+}
+
+// ParseClassifier accepts "classic" or "words".
+func ParseClassifier(s string) (Classifier, error) {
+	for cl, name := range classifierNames {
+		if s == name {
+			return Classifier(cl), nil
+		}
+	}
+	return ClassifierClassic, fmt.Errorf("unknown classifier %q: expected one of %q", s, classifierNames)
+}
@@ -0,0 +1,170 @@
+package svg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blampe/goat/internal"
+)
+
+// Stands for the prefix of the id of each <symbol>, until replaced by uniqueIDs()
+// once the diagram is drawn.  Being an entity reference never otherwise written, it
+// cannot occur in the drawing by chance.
+const idPrefix = "&goat-id;"
+
+// Replace each idPrefix in 'inner' by "goat-" and a hash of 'inner', so that the ids of
+// diagrams differing in content differ too -- as they must when inlined in one page.
+func uniqueIDs(inner []byte) []byte {
+	if !bytes.Contains(inner, []byte(idPrefix)) {
+		return inner
+	}
+	sum := sha256.Sum256(inner)
+	return bytes.ReplaceAll(inner, []byte(idPrefix),
+		[]byte("goat-" + hex.EncodeToString(sum[:4]) + "-"))
+}
+
+// symbols collects the distinct shapes drawn by reference with <use>, so that each
+// may be defined only once, as a <symbol>.
+type symbols struct {
+	ids  map[string]string  // content => id
+	defs []string           // content, in order of first use
+}
+
+// Returns the id of the <symbol> with content 'def', allocating one if necessary.
+func (s *symbols) id(def string) string {
+	if id, found := s.ids[def]; found {
+		return id
+	}
+	if s.ids == nil {
+		s.ids = make(map[string]string)
+	}
+	id := fmt.Sprintf(idPrefix + "symbol-%d", len(s.defs))
+	s.ids[def] = id
+	s.defs = append(s.defs, def)
+	return id
+}
+
+func (s *symbols) writeDefs(out io.Writer) {
+	if len(s.defs) == 0 {
+		return
+	}
+	internal.MustFPrintf(out, "<defs>\n")
+	for _, def := range s.defs {
+		// X  Without "overflow", content at negative coordinates would be clipped.
+		internal.MustFPrintf(out, `<symbol id="%s" overflow="visible">%s</symbol>
+`,
+			s.ids[def], def)
+	}
+	internal.MustFPrintf(out, "</defs>\n")
+}
+
+// Returns the attributes of a <use> element referring to the element of id 'id':
+// "href" of SVG 2, and for consumers of SVG 1.1 such as rsvg, "xlink:href".
+func useHref(out io.Writer, id string) string {
+	if d, ok := out.(*drawing); ok {
+		d.xlink = true
+	}
+	return fmt.Sprintf(`href="#%s" xlink:href="#%s"`, id, id)
+}
+
+// Returns the drawing that 'out' refers to, if the drawing is in compact mode.
+func compacting(out io.Writer) *drawing {
+	if d, ok := out.(*drawing); ok && d.compact {
+		return d
+	}
+	return nil
+}
+
+// Decimal places retained in compact mode.
+const compactDecimals = 2
+
+var (
+	// X  Numbers are rounded only within attributes of coordinates and transforms --
+	//    not e.g. within text, an href, or the data-goat-* attributes of -source-map.
+	numericAttr_re = regexp.MustCompile(` (?:d|points|x|y|x1|y1|x2|y2|cx|cy|r|rx|ry|width|height|transform)="[^"]*"`)
+	longDecimal_re = regexp.MustCompile(`-?[0-9]+\.[0-9]{` + strconv.Itoa(compactDecimals+1) + `,}`)
+	emptyGroup_re  = regexp.MustCompile(`^<g id='[^']*'>$`)
+)
+
+// compactBody strips the indentation from each line of SVG elements 'body', drops
+// <g id=...> wrappers left empty, and rounds long decimal fractions.
+func compactBody(body []byte) []byte {
+	var kept [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if string(line) == "</g>" && len(kept) > 0 && emptyGroup_re.Match(kept[len(kept)-1]) {
+			kept = kept[:len(kept)-1]
+			continue
+		}
+		kept = append(kept, line)
+	}
+	out := append(bytes.Join(kept, []byte("\n")), '\n')
+	return numericAttr_re.ReplaceAllFunc(out, func(attr []byte) []byte {
+		return longDecimal_re.ReplaceAllFunc(attr, func(match []byte) []byte {
+			v, err := strconv.ParseFloat(string(match), 64)
+			if err != nil {
+				return match
+			}
+			return []byte(coord(roundTo(v, compactDecimals)))
+		})
+	})
+}
+
+// compactCSS strips the comments, blank lines and indentation from 'style', a <style>
+// element and the CSS within it.
+// X  Comment delimiters within quoted strings are left alone, but not those within an
+//    unquoted url(), which would be rare in a diagram's stylesheet.
+func compactCSS(style string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(style); i++ {
+		ch := style[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && i+1 < len(style) {
+				b.WriteByte(ch)
+				i++
+				ch = style[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case strings.HasPrefix(style[i:], "/*"):
+			end := strings.Index(style[i+2:], "*/")
+			if end < 0 {
+				i = len(style)
+			} else {
+				i += 2 + end + 1
+			}
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	var kept []string
+	for line := range strings.Lines(b.String()) {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n") + "\n"
+}
+
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	r := math.Round(v*scale) / scale
+	if r == 0 {
+		return 0  // X  avoid "-0"
+	}
+	return r
+}
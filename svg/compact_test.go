@@ -0,0 +1,90 @@
+package svg
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCompactBody(t *testing.T) {
+	c := qt.New(t)
+
+	body := `  <g id='lines'>
+  </g>
+  <g id='circles'>
+    <circle cx="12.3456" cy="-0.001" r="6"></circle>
+  </g>
+  <g id='text'>
+    <text text-anchor="middle" x="8.12345" y="4" data-goat-row="1">pi = 3.14159</text>
+    <path d="M 0,1.0005 L 2,3" transform="rotate(90, 0.1234, 0)"></path>
+  </g>
+`
+	c.Assert(string(compactBody([]byte(body))), qt.Equals,
+		`<g id='circles'>
+<circle cx="12.35" cy="0" r="6"></circle>
+</g>
+<g id='text'>
+<text text-anchor="middle" x="8.12" y="4" data-goat-row="1">pi = 3.14159</text>
+<path d="M 0,1 L 2,3" transform="rotate(90, 0.12, 0)"></path>
+</g>
+`)
+}
+
+func TestSymbolsShared(t *testing.T) {
+	c := qt.New(t)
+
+	var s symbols
+	a := s.id("<circle r=\"6\"></circle>")
+	b := s.id("<circle r=\"4\"></circle>")
+	c.Assert(s.id("<circle r=\"6\"></circle>"), qt.Equals, a)
+	c.Assert(a, qt.Not(qt.Equals), b)
+	c.Assert(s.defs, qt.HasLen, 2)
+}
+
+func TestUseHref(t *testing.T) {
+	c := qt.New(t)
+
+	d := &drawing{compact: true}
+	ci := Circle{Start: XyIndex{1, 0}}
+	ci.Draw(d, &DefaultGeometry, 6)
+	c.Assert(d.String(), qt.Equals,
+		`<use href="#&goat-id;symbol-0" xlink:href="#&goat-id;symbol-0" x="8" y="0"/>
+`)
+	c.Assert(d.xlink, qt.IsTrue)
+	c.Assert(Viewport{XLink: true}.OpenSvgElement(), qt.Contains,
+		` xmlns:xlink="http://www.w3.org/1999/xlink" `)
+}
+
+func TestUniqueIDs(t *testing.T) {
+	c := qt.New(t)
+
+	a := string(uniqueIDs([]byte(`<symbol id="&goat-id;symbol-0"><circle r="6"/></symbol>`)))
+	b := string(uniqueIDs([]byte(`<symbol id="&goat-id;symbol-0"><circle r="4"/></symbol>`)))
+	c.Assert(a, qt.Matches, `<symbol id="goat-[0-9a-f]{8}-symbol-0">.*`)
+	c.Assert(a[:30], qt.Not(qt.Equals), b[:30])
+	c.Assert(string(uniqueIDs([]byte("<g/>"))), qt.Equals, "<g/>")
+}
+
+func TestCompactCSS(t *testing.T) {
+	c := qt.New(t)
+
+	style := `  <style type="text/css" source-text-origin="a.css">
+    /* comment */
+    .bold {
+        goat-anchor-marks: "/*"; /* ends "*/
+        content: '\'/*';
+
+        font-weight: bold;
+    }
+  </style>
+`
+	c.Assert(compactCSS(style), qt.Equals,
+		`<style type="text/css" source-text-origin="a.css">
+.bold {
+goat-anchor-marks: "/*";
+content: '\'/*';
+font-weight: bold;
+}
+</style>
+`)
+}
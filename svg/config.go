@@ -20,8 +20,67 @@ type (
 	Config struct {
 		LineFilter *regexp.Regexp
 
+		// If nil, DefaultGeometry applies.
+		Geometry *Geometry
+
+		// If nil, the <svg> element is sized to the text grid.
+		Frame *Frame
+
+		// Adjustments to the ReservedSet of the dialect, as from CSS properties
+		// "goat-text-runes" and "goat-joint-runes"; see ReservedSet().
+		RuneRoles RuneRoles
+
+		// Rules by which reserved runes are told to be text or graphics.
+		// X  Only the ASCII dialect offers a choice.
+		Classifier Classifier
+
+		// If non-zero, TABs in the input are expanded to spaces up to the next column
+		// that is a multiple of TabStop; otherwise any TAB is an error.
+		TabStop int
+
+		// If set, each line of the input is normalized to Unicode NFC, so that e.g.
+		// "e" followed by a combining accent becomes the single rune "é".
+		NFC bool
+
+		// Sub-rectangle of the input to be drawn; see CropAndTrim().
+		Rows, Cols Span
+		Trim bool
+
+		// If set, lines and arcs meeting end-to-end are drawn as a single <path>,
+		// so that CSS 'stroke-linejoin' applies, and translucent strokes do not
+		// darken where they overlap.  Otherwise each is drawn as an element of its own.
+		// Set by NewConfig(); see DefaultMergeStrokes.
+		MergeStrokes bool
+
+		// If set, output is minimized for size: circles and arrowheads are each
+		// defined once as a <symbol>, then drawn by <use>; indentation, empty
+		// groups and CSS comments are dropped; coordinates are rounded to two
+		// decimal places.
+		Compact bool
+
+		// If set, each element drawn carries attributes 'data-goat-row' and
+		// 'data-goat-col' giving the cell of the input from which it was drawn,
+		// and for lines, 'data-goat-end-row' and 'data-goat-end-col'.
+		SourceMap bool
+
+		// If set, the content of each <style> element is wrapped in an "@scope" rule
+		// without prelude, so applies only within the <svg> element -- as wanted of one
+		// inlined in an HTML page, there to style neither the page nor other diagrams.
+		//   https://developer.mozilla.org/en-US/docs/Web/CSS/@scope
+		// X  At-rules other than conditional ones, e.g. @import, @font-face and
+		//    @keyframes, are not allowed within @scope, so are dropped by browsers.
+		ScopeCSS bool
+
+		// Problems found in the diagram by NewCanvas() and WriteCanvas(), in the
+		// order found.  Check with Err() once the SVG is written: a diagram that could
+		// not be read yields an empty canvas, not a fatal error.
+		Diagnostics []Diagnostic
+
 		beginMap,
 		endMap map[rune]*markBinding
+
+		// Runes bound by "goat-glyph".
+		glyphs map[rune]*glyph
 	}
 )
 
@@ -36,7 +95,16 @@ func printfStderr(format string, args ...any) {
 }
 
 // Extend MarkBindingMap according to any GoAT-specific properties found in 'cssBytes'.
+// A relative goat-glyph-src is interpreted relative to the current directory; see
+// ParseCssFile().
 func ParseCss(bindings MarkBindingMap, cssBytes []byte) error {
+	return ParseCssFile(bindings, "", cssBytes)
+}
+
+// As ParseCss(), but a relative goat-glyph-src is interpreted relative to the directory
+// of 'name', the pathname of the CSS file -- within the embedded FS if 'name' is
+// prefixed by "embed:".
+func ParseCssFile(bindings MarkBindingMap, name string, cssBytes []byte) error {
 	parser := css.NewParser(parse.NewInputBytes(cssBytes), false)
 
 	// Running accumulation of contents of current CSS RuleSet.
@@ -93,6 +161,15 @@ func ParseCss(bindings MarkBindingMap, cssBytes []byte) error {
 			//  that requires examination.
 			markBinding = beginRuleSet(parser)
 		case css.EndRulesetGrammar:
+			if !markBinding.roles.isZero() {
+				insertRoles(bindings, markBinding.roles)
+			}
+			if markBinding.glyph != 0 || len(markBinding.glyphSrc) > 0 {
+				err := insertGlyph(bindings, markBinding)
+				if err != nil {
+					return err
+				}
+			}
 			if markBinding.markpair != zeroMarkArr {
 				if len(markBinding._idName) > 0 {
 					return fmt.Errorf("Ruleset containing %q " +
@@ -114,19 +191,61 @@ func ParseCss(bindings MarkBindingMap, cssBytes []byte) error {
 			dumpValues(grammarType)
 			cssTokens := parser.Values()
 			// 'tokenData' here is the CSS property name
+			var value string
 			switch string(tokenData) {
 			default:
 				continue   // assumed to be an ordinary property
+			case goat_anchor_marks, goat_anchor_href, goat_text_runes, goat_joint_runes,
+				goat_glyph, goat_glyph_src, goat_glyph_size:
+				var err error
+				value, err = lexDeclaration(string(tokenData), cssTokens)
+				if err != nil {
+					return err
+				}
+			}
+			switch string(tokenData) {
 			case goat_anchor_marks:
-				markStr := lexDeclaration(cssTokens)
-				markBinding.markpair = validPair(markStr)
+				var err error
+				markBinding.markpair, err = validPair(value)
+				if err != nil {
+					return err
+				}
 			//case goat_anchor_substitutes:
 			//	substStr := lexDeclaration(cssTokens)
 			//	markBinding.subst = validPair(substStr)
 			case goat_anchor_href:
 				// XX  ? Feature wanted: Rebase local links from directory of TXT source to
 				//     that of the SVG output?   
-				markBinding.HRef = lexDeclaration(cssTokens)
+				markBinding.HRef = value
+			case goat_text_runes, goat_joint_runes:
+				if !markBinding._rootRule || len(markBinding.ClassNames) > 0 {
+					return fmt.Errorf(
+						`Property %q is allowed only in a RuleSet with selector 'svg' or ':root'`,
+						string(tokenData))
+				}
+				runes := goat.MakeRuneSet([]rune(value)...)
+				if string(tokenData) == goat_text_runes {
+					markBinding.roles.TextRunes = runes
+				} else {
+					markBinding.roles.JointRunes = runes
+				}
+				continue
+			case goat_glyph:
+				runes := []rune(value)
+				if len(runes) != 1 {
+					return fmt.Errorf("%s must be a single character, found %q",
+						goat_glyph, string(runes))
+				}
+				markBinding.glyph = runes[0]
+			case goat_glyph_src:
+				markBinding.glyphSrc = resolveGlyphSrc(name, value)
+			case goat_glyph_size:
+				var err error
+				markBinding.glyphCols, markBinding.glyphRows, err =
+					parseGlyphSize(value)
+				if err != nil {
+					return err
+				}
 			}
 			// Fatal error if a GoAT-specific property is found
 			// inside a RuleSet whose CSS selector list is not names of classes, with or without a prepended element tag.
@@ -141,26 +260,35 @@ func ParseCss(bindings MarkBindingMap, cssBytes []byte) error {
 	}
 }
 
-func validPair(str string) (_ markArr) {
+func validPair(str string) (_ markArr, err error) {
 	runes := []rune(str)  // string to slice conversion
 	switch len(runes) {
 	case 0:
 		return
 	case 2:
 		// usual case
-		return markArr(runes)
+		return markArr(runes), nil
 	default:
-		log.Fatalf(
-			"invalid mark pair length): %d", len(runes))
+		return markArr{}, Diagnostic{
+			Severity: SeverityError,
+			Code:     CodeMarkPairLength,
+			Message:  fmt.Sprintf("%s %q has %d characters, expected 2: begin and end mark",
+				goat_anchor_marks, str, len(runes)),
+		}
 	}
-	return
 }
 
 // Most declarations will be parsed into only one value; an example of
 // the exceptional case would be "var(--red)"
-func lexDeclaration(cssTokens []css.Token) (tokenStr string) {
-	if len(cssTokens) > 1 {
-		log.Fatalln("could this case arise???")   
+// Read the value of the GoAT-specific 'property', which must be a single token, such
+// as a quoted string.
+func lexDeclaration(property string, cssTokens []css.Token) (tokenStr string, err error) {
+	if len(cssTokens) != 1 {
+		var found []byte
+		for _, t := range cssTokens {
+			found = append(found, t.Data...)
+		}
+		return "", fmt.Errorf("%s must have a single value, found %q", property, found)
 	}
 	tokenStr = strings.Trim(string(cssTokens[0].Data), "\"")
 	if DUMP_CSS {
@@ -173,7 +301,7 @@ func lexDeclaration(cssTokens []css.Token) (tokenStr string) {
 }
 
 func beginRuleSet(parser *css.Parser) (kb markBinding) {
-	var isClass bool
+	var isClass, isPseudoClass bool
 
 	// Consume the CSS selector list.
 	//   https://developer.mozilla.org/en-US/docs/Web/CSS/Reference/Selectors/Selector_list
@@ -196,9 +324,16 @@ func beginRuleSet(parser *css.Parser) (kb markBinding) {
 			if tokenStr == "." {
 				isClass = true
 			}
- 		case css.IdentToken: // X  hits for both a class name, and an SVG element tag, which is not needed.
-			if isClass {
+		case css.ColonToken:
+			isPseudoClass = true
+ 		case css.IdentToken: // X  hits for both a class name, and an SVG element tag.
+			switch {
+			case isClass:
 				kb.ClassNames = append(kb.ClassNames, tokenStr)
+			case isPseudoClass:
+				kb._rootRule = kb._rootRule || tokenStr == "root"
+			default:
+				kb._rootRule = kb._rootRule || tokenStr == "svg"
 			}
 		default:
 			// some other selector syntax
@@ -235,6 +370,71 @@ Attempt to overwrite HRef:
 	bindings[kb.markpair] = &kb  // escape to heap
 }
 
+// Merge 'roles' into those already declared, later declarations adding to earlier.
+func insertRoles(bindings MarkBindingMap, roles RuneRoles) {
+	kb, exists := bindings[runeRolesKey]
+	if !exists {
+		kb = &markBinding{}
+		bindings[runeRolesKey] = kb
+	}
+	union := func(s, t goat.RuneSet) goat.RuneSet {
+		if t == nil {
+			return s
+		}
+		return goat.UnionSets(s, t)
+	}
+	kb.roles.TextRunes = union(kb.roles.TextRunes, roles.TextRunes)
+	kb.roles.JointRunes = union(kb.roles.JointRunes, roles.JointRunes)
+}
+
+func insertGlyph(bindings MarkBindingMap, kb markBinding) error {
+	switch {
+	case kb.glyph == 0:
+		return fmt.Errorf("Ruleset for classes %v contained %q, but no %q.",
+			kb.ClassNames, goat_glyph_src, goat_glyph)
+	case len(kb.glyphSrc) == 0:
+		return fmt.Errorf("Ruleset for classes %v contained %q, but no %q.",
+			kb.ClassNames, goat_glyph, goat_glyph_src)
+	case kb.markpair != zeroMarkArr:
+		return fmt.Errorf("Ruleset for classes %v contained both %q and %q.",
+			kb.ClassNames, goat_glyph, goat_anchor_marks)
+	}
+	key := glyphKey(kb.glyph)
+	senior, exists := bindings[key]
+	if !exists {
+		bindings[key] = &kb  // escape to heap
+		return nil
+	}
+	if senior.glyphSrc != kb.glyphSrc {
+		return fmt.Errorf("%s '%c' bound to both %q and %q",
+			goat_glyph, kb.glyph, senior.glyphSrc, kb.glyphSrc)
+	}
+	// As for mustInsertBinding(), merge the class names.
+	senior.ClassNames = append(senior.ClassNames, kb.ClassNames...)
+	return nil
+}
+
+// ReservedSet returns the runes of 'dialectSet', the ReservedSet of a dialect, that
+// remain possibly graphical after any reassignments of c.RuneRoles.
+func (c *Config) ReservedSet(dialectSet goat.RuneSet) goat.RuneSet {
+	return c.RuneRoles.reservedSet(dialectSet)
+}
+
+func (rr RuneRoles) isZero() bool {
+	return rr.TextRunes == nil && rr.JointRunes == nil
+}
+
+func (rr RuneRoles) reservedSet(dialectSet goat.RuneSet) goat.RuneSet {
+	if rr.isZero() {
+		return dialectSet
+	}
+	set := goat.UnionSets(dialectSet, rr.JointRunes)
+	for r := range rr.TextRunes {
+		delete(set, r)
+	}
+	return set
+}
+
 func appendAttr(l, r string) string {
 	if r == "" {
 		return l
@@ -246,6 +446,10 @@ func appendAttr(l, r string) string {
 	}
 }
 
+// Whether NewConfig() sets Config.MergeStrokes, as for all drawing by cmd/goat,
+// svg.Render() and the Markdown extensions, unless asked otherwise.
+const DefaultMergeStrokes = true
+
 func NewConfig(reservedSet goat.RuneSet,
 	parsedCss MarkBindingMap,   // heavyweight object
 ) (Config, error) {
@@ -259,12 +463,26 @@ func NewConfig(reservedSet goat.RuneSet,
 	}
 
 	conf := Config{
+		MergeStrokes: DefaultMergeStrokes,
+
 		// Copies of arg 'parsedCss', with possible edits.
 		beginMap: make(map[rune]*markBinding),
 		endMap:   make(map[rune]*markBinding),
+		glyphs:   make(map[rune]*glyph),
 	}
 	for markpair, kb := range parsedCss {
-		_ = markpair
+		if markpair == runeRolesKey {
+			conf.RuneRoles = kb.roles
+			continue
+		}
+		if isGlyphKey(markpair) {
+			gl, err := loadGlyph(kb)
+			if err != nil {
+				return Config{}, err
+			}
+			conf.glyphs[kb.glyph] = gl
+			continue
+		}
 		// Verify that kb.markpair[0] and kb.markpair[1] are both unique
 		// across conf.MarkBindingMap, to avoid silently "orphaning" markBinding definitions.
 		allocIfUnique := func(beMap map[rune]*markBinding, be rune) error {
@@ -290,7 +508,33 @@ func NewConfig(reservedSet goat.RuneSet,
 }
 
 func vetMarkBindingMap(reservedSet goat.RuneSet, parsedCss MarkBindingMap) (err error) {
+	if kb, found := parsedCss[runeRolesKey]; found {
+		err = vetRuneRoles(kb.roles)
+		if err != nil {
+			return err
+		}
+		// Marks may be neither of the runes newly made reserved.
+		reservedSet = kb.roles.reservedSet(reservedSet)
+	}
 	for markRunes, kb := range parsedCss {
+		if markRunes == runeRolesKey {
+			continue
+		}
+		if isGlyphKey(markRunes) {
+			// A glyph must be text, and not also a mark.
+			err = vetMark(reservedSet, kb, kb.glyph)
+			if err != nil {
+				return err
+			}
+			for otherRunes := range parsedCss {
+				if !isGlyphKey(otherRunes) &&
+					(otherRunes[0] == kb.glyph || otherRunes[1] == kb.glyph) {
+					return fmt.Errorf("rune '%c' (0x%x) cannot be both a %s and a mark of %s",
+						kb.glyph, kb.glyph, goat_glyph, goat_anchor_marks)
+				}
+			}
+			continue
+		}
 		if markRunes == zeroMarkArr {
 			return errors.New(fmt.Sprintf(
 				"invalid markpair (%s)", markRunes.String()))
@@ -308,13 +552,45 @@ func vetMarkBindingMap(reservedSet goat.RuneSet, parsedCss MarkBindingMap) (err
 	return
 }
 
+func loadGlyph(kb *markBinding) (*glyph, error) {
+	bytes, err := readGlyphSrc(kb.glyphSrc)
+	if err != nil {
+		return nil, fmt.Errorf("%s of '%c': %v", goat_glyph_src, kb.glyph, err)
+	}
+	gl, err := newGlyph(kb.glyphSrc, bytes)
+	if err != nil {
+		return nil, err
+	}
+	gl.ClassNames = kb.ClassNames
+	gl.cols, gl.rows = max(kb.glyphCols, 1), max(kb.glyphRows, 1)
+	// X  Derived from the rune rather than a class name, being unique among glyphs.
+	gl.symbolID = fmt.Sprintf(idPrefix + "glyph-%x", kb.glyph)
+	return gl, nil
+}
+
+func vetRuneRoles(roles RuneRoles) error {
+	for r := range roles.JointRunes {
+		switch {
+		case roles.TextRunes.Contains(r):
+			return fmt.Errorf(`rune '%c' (0x%x) cannot be in both %s and %s`,
+				r, r, goat_text_runes, goat_joint_runes)
+		case r == ' ':
+			return fmt.Errorf(`SPACE cannot be in %s`, goat_joint_runes)
+		}
+	}
+	return nil
+}
+
 func vetMark(reservedSet goat.RuneSet, candidateMarkBinding *markBinding, r rune) error {
 	_, found := reservedSet[r]
 	if found {
-		return errors.New(fmt.Sprintf(
-			`reserved rune '%c' (0x%x) ` +
-				`cannot be used as a markpairclass mark by markpairclass: %s`,
-			r, r, formatMarkBinding(candidateMarkBinding)))
+		return Diagnostic{
+			Severity: SeverityError,
+			Code:     CodeReservedMark,
+			Message:  fmt.Sprintf("reserved rune '%c' (0x%x) cannot be a mark of %s," +
+				" in rule %s: it may be drawn as graphics",
+				r, r, goat_anchor_marks, candidateMarkBinding.classSelector()),
+		}
 	}
 	return nil
 }
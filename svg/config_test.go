@@ -0,0 +1,64 @@
+package svg
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/blampe/goat"
+)
+
+func TestRuneRoles(t *testing.T) {
+	c := qt.New(t)
+	dialectSet := goat.MakeRuneSet('-', '|', '+', 'o', 'v', ' ')
+
+	bindings := make(MarkBindingMap)
+	err := ParseCss(bindings, []byte(`
+:root { goat-text-runes: "o"; }
+svg   { goat-text-runes: "v"; goat-joint-runes: "#"; }
+.em   { goat-anchor-marks: "[]"; }
+`))
+	c.Assert(err, qt.IsNil)
+	config, err := NewConfig(dialectSet, bindings)
+	c.Assert(err, qt.IsNil)
+	c.Assert(config.RuneRoles.TextRunes, qt.DeepEquals, goat.MakeRuneSet('o', 'v'))
+	c.Assert(config.ReservedSet(dialectSet), qt.DeepEquals, goat.MakeRuneSet('-', '|', '+', '#', ' '))
+	c.Assert(config.beginMap['['], qt.IsNotNil)
+
+	// Only 'svg' or ':root' rules may assign roles.
+	err = ParseCss(make(MarkBindingMap), []byte(`.x { goat-text-runes: "o"; }`))
+	c.Assert(err, qt.ErrorMatches, `.*allowed only in a RuleSet with selector 'svg' or ':root'`)
+
+	// A rune newly made reserved cannot be a mark.
+	bindings = make(MarkBindingMap)
+	err = ParseCss(bindings, []byte(`
+svg { goat-joint-runes: "#"; }
+.em { goat-anchor-marks: "#]"; }
+`))
+	c.Assert(err, qt.IsNil)
+	_, err = NewConfig(dialectSet, bindings)
+	c.Assert(err, qt.ErrorMatches, `(?s).*reserved rune '#' .*`)
+
+	bindings = make(MarkBindingMap)
+	err = ParseCss(bindings, []byte(`svg { goat-text-runes: "o"; goat-joint-runes: "o"; }`))
+	c.Assert(err, qt.IsNil)
+	_, err = NewConfig(dialectSet, bindings)
+	c.Assert(err, qt.ErrorMatches, `rune 'o' .* cannot be in both .*`)
+}
+
+func TestDeclarationValue(t *testing.T) {
+	c := qt.New(t)
+
+	for _, decl := range []string{
+		`svg { goat-text-runes: ; }`,
+		`svg { goat-joint-runes: "#" "*"; }`,
+		`.g { goat-glyph: ; goat-glyph-src: "x.svg"; }`,
+		`.g { goat-glyph: "G"; goat-glyph-src: ; }`,
+		`.g { goat-glyph: "G"; goat-glyph-src: "x.svg"; goat-glyph-size: 2 x 1; }`,
+		`.em { goat-anchor-marks: ; }`,
+	} {
+		err := ParseCss(make(MarkBindingMap), []byte(decl))
+		c.Assert(err, qt.ErrorMatches, `goat-[a-z-]+ must have a single value, found .*`,
+			qt.Commentf("%s", decl))
+	}
+}
@@ -0,0 +1,137 @@
+package svg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Span selects an inclusive range of rows or columns of the input diagram,
+// numbered from 1 as by a text editor.  Zero in either field leaves that end unbounded.
+type Span struct {
+	First, Last int
+}
+
+// ParseSpan accepts "FIRST:LAST", where either number may be omitted, e.g. "10:40" or "5:".
+func ParseSpan(s string) (sp Span, err error) {
+	first, last, found := strings.Cut(s, ":")
+	if !found {
+		return Span{}, fmt.Errorf("invalid range %q: expected FIRST:LAST", s)
+	}
+	parse := func(f string) (int, error) {
+		if len(f) == 0 {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid range %q: %q is not a number 1 or greater", s, f)
+		}
+		return n, nil
+	}
+	if sp.First, err = parse(first); err != nil {
+		return Span{}, err
+	}
+	if sp.Last, err = parse(last); err != nil {
+		return Span{}, err
+	}
+	if sp.Last != 0 && sp.Last < sp.First {
+		return Span{}, fmt.Errorf("invalid range %q: end precedes beginning", s)
+	}
+	return
+}
+
+// Returns the half-open, zero-based interval selected from [0, limit).
+func (sp Span) bounds(limit int) (lo, hi int) {
+	lo, hi = 0, limit
+	if sp.First > 0 {
+		lo = min(sp.First-1, limit)
+	}
+	if sp.Last > 0 {
+		hi = min(sp.Last, limit)
+	}
+	return
+}
+
+// CropAndTrim reduces the canvas to the sub-rectangle selected by config.Rows and
+// config.Cols, then if config.Trim is set, further removes blank rows and
+// columns from all four sides.
+//
+// X  Called after MoveToText(), so that runes at the edges of the sub-rectangle
+//    are classified as text or graphics in the context of the full diagram.
+//    Graphics are recognized only within the sub-rectangle.
+func CropAndTrim(config *Config, ac AbstractCanvas) {
+	cc := ac.GetCommon()
+	x0, x1 := config.Cols.bounds(cc.Width)
+	y0, y1 := config.Rows.bounds(cc.Height)
+	cc.crop(x0, y0, x1, y1)
+
+	if !config.Trim {
+		return
+	}
+	var blank = true
+	for idx := range LeftRightMinor(cc.Width, cc.Height) {
+		if r := cc.runeAnywhereAt(idx); r == ' ' {
+			continue
+		}
+		if blank {
+			x0, y0, x1, y1 = idx.X, idx.Y, idx.X+1, idx.Y+1
+			blank = false
+			continue
+		}
+		x0, y0 = min(x0, idx.X), min(y0, idx.Y)
+		x1, y1 = max(x1, idx.X+1), max(y1, idx.Y+1)
+	}
+	if blank {
+		x0, y0, x1, y1 = 0, 0, 0, 0
+	}
+	cc.crop(x0, y0, x1, y1)
+}
+
+// Returns the rune written at 'i' whether text or not, or ' ' if none; or for the
+// second cell of a wide character, 'wideTail'.
+func (c *CanvasCommon) runeAnywhereAt(i XyIndex) rune {
+	n, ok := c.offsetOf(i)
+	if !ok || c.cells[n] == absent {
+		return ' '
+	}
+	return c.cells[n]
+}
+
+// Retain only the cells within the half-open rectangle [x0,x1) x [y0,y1), shifted
+// so that cell {x0,y0} becomes {0,0}.
+func (c *CanvasCommon) crop(x0, y0, x1, y1 int) {
+	if x0 == 0 && y0 == 0 && x1 == c.Width && y1 == c.Height {
+		return
+	}
+	width, height := x1-x0, y1-y0
+	cells := make([]rune, width*height)
+	isText := newBitmap(len(cells))
+	var isJoint bitmap
+	if c.isJoint != nil {
+		isJoint = newBitmap(len(cells))
+	}
+	var clusters map[int]string
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			from, _ := c.offsetOf(XyIndex{x, y})
+			to := (y-y0)*width + (x-x0)
+			cells[to] = c.cells[from]
+			if c.isText.get(from) {
+				isText.set(to)
+			}
+			if c.isJoint.get(from) {
+				isJoint.set(to)
+			}
+			if s, found := c.clusters[from]; found {
+				if clusters == nil {
+					clusters = make(map[int]string)
+				}
+				clusters[to] = s
+			}
+		}
+	}
+	c.cells, c.isText, c.isJoint, c.clusters = cells, isText, isJoint, clusters
+	c.Width, c.Height = width, height
+	c.Offset.X += x0
+	c.Offset.Y += y0
+}
@@ -2,6 +2,7 @@ package svg
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Embed arg 'source' in the neo-attribute 'goat-source', which the browser merely
@@ -15,6 +16,27 @@ func newStyleElement(sourceOrigin, css string) string {
 		css)
 }
 
+// If config.ScopeCSS, wrap 'css' in an "@scope" rule, which without prelude is rooted
+// at the parent of the <style> element, the <svg>.
+func scopedCSS(config *Config, css string) string {
+	if !config.ScopeCSS {
+		return css
+	}
+	var b strings.Builder
+	b.WriteString("    @scope {\n")
+	for line := range strings.Lines(css) {
+		if len(strings.TrimSpace(line)) > 0 {
+			b.WriteString("    ")
+		}
+		b.WriteString(line)
+	}
+	if !strings.HasSuffix(css, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("    }\n")
+	return b.String()
+}
+
 // See:
 //   https://drafts.csswg.org/mediaqueries-5/#prefers-color-scheme
 //   https://developer.mozilla.org/en-US/docs/Web/SVG/Element/style
@@ -0,0 +1,38 @@
+package svg
+
+import (
+	"bytes"
+	"math"
+)
+
+// Lines of any length are read whole, buffer space permitting.
+const maxLineLength = math.MaxInt32
+
+var byteOrderMark = []byte("\uFEFF")
+
+// Like bufio.ScanLines, a bufio.SplitFunc returning each line of text stripped of its
+// end-of-line marker; but a marker may be "\r\n", "\n", or a lone "\r", as written by
+// classic Mac OS.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		switch {
+		case data[i] == '\n':
+			return i + 1, data[:i], nil
+		case i+1 < len(data) && data[i+1] == '\n':
+			return i + 2, data[:i], nil
+		case i+1 < len(data) || atEOF:
+			return i + 1, data[:i], nil
+		default:
+			// Whether "\r" begins "\r\n" is not yet known.
+			return 0, nil, nil
+		}
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	// Request more data.
+	return 0, nil, nil
+}
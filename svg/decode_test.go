@@ -0,0 +1,50 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDecoding(t *testing.T) {
+	c := qt.New(t)
+
+	rows := func(cc CanvasCommon) (s []string) {
+		for y := 0; y < cc.Height; y++ {
+			var row []rune
+			for x := 0; x < cc.Width; x++ {
+				if r, ok := cc.DataRuneAt(XyIndex{x, y}); ok {
+					row = append(row, r)
+				}
+			}
+			s = append(s, string(row))
+		}
+		return
+	}
+
+	// A byte order mark is dropped; lines may end in CRLF, LF or CR.
+	config := Config{}
+	cc := NewCanvasCommon(&config, strings.NewReader("\uFEFFab\r\ncd\ref\n\ngh\r"))
+	c.Assert(rows(cc), qt.DeepEquals, []string{"ab", "cd", "ef", "", "gh"})
+	c.Assert(config.Diagnostics, qt.HasLen, 0)
+
+	// Lines are not limited to bufio.MaxScanTokenSize.
+	long := strings.Repeat("-", 100000)
+	cc = NewCanvasCommon(&config, strings.NewReader(long + "\n+"))
+	c.Assert(cc.Width, qt.Equals, len(long))
+	c.Assert(cc.Height, qt.Equals, 2)
+
+	// Invalid bytes are reported by position.
+	cc = NewCanvasCommon(&config, strings.NewReader("ok\na\xffb\n"))
+	c.Assert(rows(cc)[1], qt.Equals, "a\uFFFDb")
+	c.Assert(config.Err(), qt.IsNil)
+	c.Assert(config.Diagnostics[0].Error(), qt.Equals,
+		"2:2: warning: invalid UTF-8 byte 0xff, drawn as U+FFFD [input/invalid-utf8]")
+
+	// NFC composes "e" and COMBINING ACUTE ACCENT.
+	config = Config{NFC: true}
+	cc = NewCanvasCommon(&config, strings.NewReader("e\u0301|"))
+	c.Assert(rows(cc), qt.DeepEquals, []string{"\u00E9|"})
+	c.Assert(cc.clusters, qt.IsNil)
+}
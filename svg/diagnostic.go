@@ -0,0 +1,148 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity ranks a Diagnostic.  Only errors call for a failing exit status.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// MarshalText renders 's' in JSON as would String().
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Codes of Diagnostics, each prefixed by its class: "input" for problems of the
+// diagram, "css" of the stylesheets, "io" of reading the diagram, and "lint" for
+// constructs that are valid but likely mistakes, as found by Lint().
+const (
+	CodeTab            = "input/tab"
+	CodeTabExpanded    = "input/tab-expanded"
+	CodeInvalidUTF8    = "input/invalid-utf8"
+	CodeEmptyInput     = "input/empty"
+	CodeUnmatchedMark  = "input/unmatched-mark"
+	CodeUnclosedMark   = "input/unclosed-mark"
+	CodeReservedMark   = "css/reserved-mark"
+	CodeMarkPairLength = "css/mark-pair-length"
+	CodeCSS            = "css/invalid"
+	CodeIO             = "io/read"
+
+	CodeTrailingSpace    = "lint/trailing-whitespace"
+	CodeRowTooWide       = "lint/row-too-wide"
+	CodeNestedAnchor     = "lint/nested-anchor"
+	CodeDanglingLine     = "lint/dangling-line"
+	CodeLoneJoint        = "lint/lone-joint"
+	CodeOneArmedJoint    = "lint/one-armed-joint"
+	CodeLooseArrowhead   = "lint/loose-arrowhead"
+	CodeLetterAsGraphics = "lint/letter-as-graphics"
+)
+
+// A Diagnostic reports a problem located in a diagram or stylesheet.
+// It satisfies 'error', so may also be returned as one.
+type Diagnostic struct {
+	// Empty if not known, e.g. for a diagram read from a pipe.
+	File string `json:"file,omitempty"`
+
+	// Counting from 1 as by a text editor; 0 if not known.
+	Line int `json:"line,omitempty"`
+	Col  int `json:"col,omitempty"`
+
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// Error formats 'd' as would a compiler, "FILE:LINE:COL: SEVERITY: MESSAGE [CODE]",
+// omitting such of the position as is not known.
+func (d Diagnostic) Error() string {
+	var b strings.Builder
+	if len(d.File) > 0 {
+		b.WriteString(d.File + ":")
+	}
+	if d.Line > 0 {
+		fmt.Fprintf(&b, "%d:", d.Line)
+		if d.Col > 0 {
+			fmt.Fprintf(&b, "%d:", d.Col)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(&b, "%s: %s [%s]", d.Severity, d.Message, d.Code)
+	return b.String()
+}
+
+// Class returns the prefix of d.Code, e.g. "input".
+func (d Diagnostic) Class() string {
+	class, _, _ := strings.Cut(d.Code, "/")
+	return class
+}
+
+// AsDiagnostic returns the Diagnostic wrapped by 'err' if any, else a Diagnostic of
+// severity error bearing 'code' and the text of 'err'.
+func AsDiagnostic(err error, code string) Diagnostic {
+	var d Diagnostic
+	if errors.As(err, &d) {
+		return d
+	}
+	return Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  err.Error(),
+	}
+}
+
+// Err returns the Diagnostics of severity error recorded in 'c', joined, or nil
+// if there are none.
+func (c *Config) Err() error {
+	var errs []error
+	for _, d := range c.Diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Diagnose records in config.Diagnostics a problem found at cell 'i'.
+func (c *CanvasCommon) Diagnose(config *Config, i XyIndex, severity Severity, code, format string, args ...any) {
+	line, col := c.Position(i)
+	config.Diagnostics = append(config.Diagnostics, Diagnostic{
+		File:     c.file,
+		Line:     line,
+		Col:      col,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Position returns the line and column of the input, counting from 1, of cell 'i'.
+// Lines discarded by Config.LineFilter are counted; columns count runes.
+func (c *CanvasCommon) Position(i XyIndex) (line, col int) {
+	y := c.Offset.Y + i.Y
+	line = y + 1
+	if y >= 0 && y < len(c.lineNumbers) {
+		line = c.lineNumbers[y]
+	}
+	return line, c.Offset.X + i.X + 1
+}
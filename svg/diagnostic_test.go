@@ -0,0 +1,62 @@
+package svg
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/blampe/goat"
+)
+
+// Treats every rune as text.
+type allText struct {
+	CanvasCommon
+}
+
+func (c *allText) GetCommon() *CanvasCommon           { return &c.CanvasCommon }
+func (c *allText) WriteSVGBody(io.Writer, *Config)     {}
+func (c *allText) ShouldMoveToTextRunes(XyIndex) bool { return true }
+
+func TestDiagnostics(t *testing.T) {
+	c := qt.New(t)
+
+	bindings := make(MarkBindingMap)
+	err := ParseCss(bindings, []byte(`.em { goat-anchor-marks: "[]"; }`))
+	c.Assert(err, qt.IsNil)
+	config, err := NewConfig(goat.MakeRuneSet(' '), bindings)
+	c.Assert(err, qt.IsNil)
+	config.LineFilter = regexp.MustCompile(`^[^#]`)
+	ac := &allText{NewCanvasCommon(&config, strings.NewReader("# comment\n a]\n [b\n"))}
+	MoveToText(ac)
+	var out bytes.Buffer
+	Writetext(&out, &config, ac)
+	c.Assert(config.Err(), qt.ErrorMatches,
+		`(?s)2:3: error: end mark '\]' of \.em, but no begin mark '\[' open \[input/unmatched-mark\]\n`+
+			`3:2: error: begin mark '\[' of \.em never closed by end mark '\]' \[input/unclosed-mark\]`)
+	// The unclosed <g> is closed nonetheless.
+	c.Assert(strings.Count(out.String(), "<g"), qt.Equals, strings.Count(out.String(), "</g>"))
+
+	// Links may not nest.
+	bindings = make(MarkBindingMap)
+	err = ParseCss(bindings, []byte(`
+.a { goat-anchor-marks: "{}"; goat-anchor-href: "https://a.example"; }
+.b { goat-anchor-marks: "<>"; goat-anchor-href: "https://b.example"; }
+`))
+	c.Assert(err, qt.IsNil)
+	config, err = NewConfig(goat.MakeRuneSet(' '), bindings)
+	c.Assert(err, qt.IsNil)
+	ac = &allText{NewCanvasCommon(&config, strings.NewReader("{x<y>}"))}
+	MoveToText(ac)
+	Writetext(io.Discard, &config, ac)
+	c.Assert(config.Err(), qt.IsNil)
+	c.Assert(config.Diagnostics, qt.HasLen, 1)
+	c.Assert(config.Diagnostics[0].Code, qt.Equals, CodeNestedAnchor)
+	c.Assert(config.Diagnostics[0].Col, qt.Equals, 3)
+
+	_, err = validPair("{}x")
+	c.Assert(AsDiagnostic(err, CodeCSS).Code, qt.Equals, CodeMarkPairLength)
+}
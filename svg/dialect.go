@@ -0,0 +1,83 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/blampe/goat"
+)
+
+// NewCanvasFunc reads a diagram from 'in', returning a canvas ready for WriteCanvas().
+// The NewCanvas() functions of packages ascii and utf8 are examples; like them, it
+// should read the diagram by ReadCanvas(), lest it ignore -rows, -cols, -trim or
+// "goat-joint-runes".
+type NewCanvasFunc func(config *Config, in io.Reader) AbstractCanvas
+
+// A Dialect is a flavor of text-art diagram: the runes it reserves for graphics, and
+// the function parsing it.
+type Dialect struct {
+	Name        string
+	ReservedSet goat.RuneSet
+	NewCanvas   NewCanvasFunc
+}
+
+// X  Lives here rather than in package goat, which package svg imports.
+var dialects struct {
+	sync.RWMutex
+	byName map[string]*Dialect
+}
+
+// RegisterDialect makes a dialect available by 'name' to LookupDialect(), and so to
+// Render(), the -dialect option of cmd/goat, and internal/testlib.
+// Packages defining a dialect commonly call it from an init() function, as do
+// packages ascii and utf8; programs then need only import such a package, if
+// necessary for its side effect alone:
+//
+//	import _ "example.com/mydialect"
+//
+// RegisterDialect panics if 'name' is empty or already registered, or 'newCanvas' is nil.
+func RegisterDialect(name string, reservedSet goat.RuneSet, newCanvas NewCanvasFunc) {
+	if len(name) == 0 || newCanvas == nil {
+		panic("svg.RegisterDialect: empty name or nil NewCanvasFunc")
+	}
+	dialects.Lock()
+	defer dialects.Unlock()
+	if dialects.byName == nil {
+		dialects.byName = make(map[string]*Dialect)
+	}
+	if _, found := dialects.byName[name]; found {
+		panic(fmt.Sprintf("svg.RegisterDialect: dialect %q registered twice", name))
+	}
+	dialects.byName[name] = &Dialect{
+		Name:        name,
+		ReservedSet: reservedSet,
+		NewCanvas:   newCanvas,
+	}
+}
+
+// LookupDialect returns the dialect registered as 'name'.
+func LookupDialect(name string) (*Dialect, error) {
+	dialects.RLock()
+	d, found := dialects.byName[name]
+	dialects.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown dialect %q: registered dialects are %s",
+			name, strings.Join(DialectNames(), ", "))
+	}
+	return d, nil
+}
+
+// DialectNames returns the names of all registered dialects, sorted.
+func DialectNames() []string {
+	dialects.RLock()
+	defer dialects.RUnlock()
+	names := make([]string, 0, len(dialects.byName))
+	for name := range dialects.byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -6,7 +6,7 @@ import (
 
 // Drawable represents anything that can Draw itself.
 type Drawable interface {
-	Draw(out io.Writer)
+	Draw(out io.Writer, g *Geometry)
 }
 
 // XX  drop names 'start' below
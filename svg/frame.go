@@ -0,0 +1,327 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Frame controls the size and placement of the drawing within the <svg> element.
+// The zero value reproduces the historical output: a viewBox derived from the
+// dimensions of the text grid, in CSS pixels.
+type Frame struct {
+	// If true, the viewBox is fitted to the extents of the elements actually drawn,
+	// rather than to the text grid.
+	Tight bool
+
+	// Space added around the drawing, in units of the viewBox.
+	Margin Margins
+
+	// Multiplier from viewBox units to CSS pixels of the 'width' and 'height'
+	// attributes of <svg>.  Zero is taken as 1.
+	Scale float64
+
+	// If non-zero, the 'width' and/or 'height' attributes of <svg>.
+	// Should only one be given, the other follows from the aspect ratio of the viewBox.
+	Width, Height Length
+
+	// If non-zero, an upper limit on 'width', applied with the aspect ratio preserved.
+	MaxWidth Length
+}
+
+// Margins in CSS order: top, right, bottom, left.
+type Margins [4]float64
+
+// ParseMargins accepts one, two, three or four comma-separated numbers, with the
+// meaning of the CSS 'margin' shorthand property.
+func ParseMargins(s string) (m Margins, err error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return
+	}
+	fields := strings.Split(s, ",")
+	var v []float64
+	for _, f := range fields {
+		n, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return Margins{}, fmt.Errorf("invalid margin %q: %v", s, err)
+		}
+		v = append(v, n)
+	}
+	switch len(v) {
+	case 1:
+		m = Margins{v[0], v[0], v[0], v[0]}
+	case 2:
+		m = Margins{v[0], v[1], v[0], v[1]}
+	case 3:
+		m = Margins{v[0], v[1], v[2], v[1]}
+	case 4:
+		m = Margins{v[0], v[1], v[2], v[3]}
+	default:
+		return Margins{}, fmt.Errorf("invalid margin %q: expected 1 to 4 values", s)
+	}
+	return
+}
+
+func (m Margins) top() float64    { return m[0] }
+func (m Margins) right() float64  { return m[1] }
+func (m Margins) bottom() float64 { return m[2] }
+func (m Margins) left() float64   { return m[3] }
+
+// Length is an SVG length attribute value, e.g. "120mm".  An empty Unit means CSS pixels.
+type Length struct {
+	Value float64
+	Unit  string
+}
+
+// CSS absolute units, per https://www.w3.org/TR/css-values-3/#absolute-lengths
+var pxPerUnit = map[string]float64{
+	"":   1,
+	"px": 1,
+	"in": 96,
+	"cm": 96 / 2.54,
+	"mm": 96 / 25.4,
+	"pt": 96.0 / 72,
+	"pc": 96.0 / 6,
+}
+
+// ParseLength accepts a number optionally followed by one of the units
+// px, in, cm, mm, pt or pc.
+func ParseLength(s string) (Length, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return Length{}, nil
+	}
+	numEnd := strings.LastIndexAny(s, "0123456789.") + 1
+	unit := s[numEnd:]
+	if _, found := pxPerUnit[unit]; !found {
+		return Length{}, fmt.Errorf("invalid length %q: unknown unit %q", s, unit)
+	}
+	v, err := strconv.ParseFloat(s[:numEnd], 64)
+	if err != nil {
+		return Length{}, fmt.Errorf("invalid length %q: %v", s, err)
+	}
+	if v <= 0 {
+		return Length{}, fmt.Errorf("invalid length %q: must be positive", s)
+	}
+	return Length{v, unit}, nil
+}
+
+func (l Length) IsZero() bool {
+	return l.Value == 0
+}
+
+func (l Length) px() float64 {
+	return l.Value * pxPerUnit[l.Unit]
+}
+
+// X  Rounded, because values derived from an aspect ratio may otherwise print
+//    with spurious digits e.g. "63.99999999999999mm".
+func (l Length) String() string {
+	return coord(math.Round(l.Value*1000)/1000) + l.Unit
+}
+
+// Returns 'l' scaled by 'f', in the same unit.
+func (l Length) times(f float64) Length {
+	return Length{l.Value * f, l.Unit}
+}
+
+// Extents accumulates the bounding box, in the coordinates of the drawing, of the
+// SVG elements written.
+type Extents struct {
+	Min, Max Pixel
+	nonEmpty bool
+}
+
+// The SVG default 'stroke-width' is 1.  CSS specifying wider strokes should be
+// accompanied by a Frame.Margin.
+const halfStroke = 0.5
+
+func (e *Extents) IsEmpty() bool {
+	return !e.nonEmpty
+}
+
+func (e *Extents) Include(points ...Pixel) {
+	for _, p := range points {
+		if !e.nonEmpty {
+			e.Min, e.Max = p, p
+			e.nonEmpty = true
+			continue
+		}
+		e.Min.X = min(e.Min.X, p.X)
+		e.Min.Y = min(e.Min.Y, p.Y)
+		e.Max.X = max(e.Max.X, p.X)
+		e.Max.Y = max(e.Max.Y, p.Y)
+	}
+}
+
+// Include a box centered on each of 'points', of half-dimensions 'halfSize'.
+func (e *Extents) IncludeBox(halfSize Pixel, points ...Pixel) {
+	for _, p := range points {
+		e.Include(
+			Pixel{p.X - halfSize.X, p.Y - halfSize.Y},
+			Pixel{p.X + halfSize.X, p.Y + halfSize.Y})
+	}
+}
+
+// Include 'points' of a stroked element, allowing for the stroke width.
+func (e *Extents) IncludeStroked(points ...Pixel) {
+	e.IncludeBox(Pixel{halfStroke, halfStroke}, points...)
+}
+
+// drawing buffers the SVG elements of a canvas body, while tracking their Extents.
+type drawing struct {
+	bytes.Buffer
+	Extents
+
+	// If set, strokes are collected into 'strokes' rather than written to the Buffer.
+	mergeStrokes bool
+	strokes []stroke
+
+	// If set, repeated shapes are drawn by reference to 'symbols'.
+	compact bool
+	symbols symbols
+
+	// If set, elements carry attributes locating their origin in the input diagram,
+	// as found by the Position() of 'source'.
+	sourceMap bool
+	source *CanvasCommon
+
+	// Set if any element refers to another by "xlink:href"; see useHref().
+	xlink bool
+}
+
+// Returns an empty slice to which an element may be appended, then written to 'out'
+// by writeElement().
+// X  Formatted thus into the spare capacity of a *drawing, rather than by Fprintf(),
+//    the commonest elements are drawn without allocation.
+func elementBuffer(out io.Writer) []byte {
+	if d, ok := out.(*drawing); ok {
+		return d.AvailableBuffer()
+	}
+	return nil
+}
+
+func writeElement(out io.Writer, b []byte) {
+	if _, err := out.Write(b); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Record the extents of an element just written to 'out', if 'out' tracks Extents.
+func extend(out io.Writer, f func(*Extents)) {
+	if d, ok := out.(*drawing); ok {
+		f(&d.Extents)
+	}
+}
+
+// Viewport is the outermost coordinate transformation of the SVG output:
+// the size of the <svg> element, its viewBox, and the translation applied to
+// the drawing within it.
+type Viewport struct {
+	Width, Height Length
+
+	// The viewBox always begins at {0,0}.
+	ViewBox Pixel
+
+	// Position within the viewBox of the pixel coordinate {0,0} of the drawing.
+	Origin Pixel
+
+	// If set, the namespace of "xlink:href" is declared.
+	XLink bool
+}
+
+// Viewport places the drawing within the <svg> element according to 'config',
+// given the extents of what was drawn.
+func (cc *CanvasCommon) Viewport(config *Config, drawn *Extents) (v Viewport) {
+	g := config.Geom()
+	f := config.Frame
+	if f == nil {
+		f = &Frame{}
+	}
+
+	if f.Tight && !drawn.IsEmpty() {
+		v.Origin = Pixel{-drawn.Min.X, -drawn.Min.Y}
+		v.ViewBox = Pixel{drawn.Max.X - drawn.Min.X, drawn.Max.Y - drawn.Min.Y}
+	} else {
+		// We desire that pixel coordinate {0,0} should lie at the *center* of the
+		// "cell" at top-left corner of the enclosing SVG element, and that a
+		// visually-pleasing margin separate that cell from the visible top-left
+		// corner.
+		//
+		// X The former 16-pixel Y-axis translation was more than necessary – there
+		// is an always-blank band at the top of SVG image.
+		v.Origin = Pixel{g.CellWidth, g.CellHeight*3/4}
+		v.ViewBox = Pixel{cc.widthScreen(g), cc.heightScreen(g)}
+	}
+	m := f.Margin
+	v.Origin.Delta(Pixel{m.left(), m.top()})
+	v.ViewBox.Delta(Pixel{m.left() + m.right(), m.top() + m.bottom()})
+
+	scale := f.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	aspect := v.ViewBox.Y / v.ViewBox.X
+	switch {
+	case !f.Width.IsZero() && !f.Height.IsZero():
+		v.Width, v.Height = f.Width, f.Height
+	case !f.Width.IsZero():
+		v.Width, v.Height = f.Width, f.Width.times(aspect)
+	case !f.Height.IsZero():
+		v.Width, v.Height = f.Height.times(1/aspect), f.Height
+	default:
+		v.Width, v.Height = Length{Value: v.ViewBox.X * scale}, Length{Value: v.ViewBox.Y * scale}
+	}
+	if !f.MaxWidth.IsZero() && v.Width.px() > f.MaxWidth.px() {
+		v.Width, v.Height = f.MaxWidth, f.MaxWidth.times(aspect)
+	}
+	return
+}
+
+func (c *CanvasCommon) heightScreen(g *Geometry) float64 {
+	// " + H/2 + H/8", because any less results in clipping of any edge at the bottom of the drawing
+	//    XX  Necessary because fragilely tuned to 'Origin', above.
+	return float64(c.Height)*g.CellHeight + g.CellHeight/2 + g.CellHeight/8
+}
+
+func (c *CanvasCommon) widthScreen(g *Geometry) float64 {
+	// XX  "c.Width + 1", fragilely tuned to 'Origin', above.
+	return float64(c.Width + 1) * g.CellWidth
+}
+
+func (v Viewport) OpenSvgElement() string {
+	var xlink string
+	if v.XLink {
+		xlink = ` xmlns:xlink="http://www.w3.org/1999/xlink"`
+	}
+	return fmt.Sprintf(
+`<svg xmlns="http://www.w3.org/2000/svg"%s version="1.1"
+    width="%s" height="%s"
+    viewBox="0 0 %s %s">
+`,
+		xlink,
+		v.Width, v.Height,
+		coord(v.ViewBox.X), coord(v.ViewBox.Y),
+	)
+}
+
+func (v Viewport) OpenGElement() string {
+	return fmt.Sprintf(`
+<g transform='translate(%s)'>
+`,
+		v.Origin)
+}
+
+// Rotate 'p' by 'degrees' clockwise about 'center', as does the SVG transform 'rotate()'.
+func rotate(p Pixel, degrees float64, center Pixel) Pixel {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	dx, dy := p.X-center.X, p.Y-center.Y
+	return Pixel{
+		center.X + dx*cos - dy*sin,
+		center.Y + dx*sin + dy*cos,
+	}
+}
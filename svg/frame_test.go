@@ -0,0 +1,55 @@
+package svg
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseFrameOptions(t *testing.T) {
+	c := qt.New(t)
+
+	l, err := ParseLength("120mm")
+	c.Assert(err, qt.IsNil)
+	c.Assert(l, qt.Equals, Length{120, "mm"})
+	c.Assert(l.String(), qt.Equals, "120mm")
+
+	l, err = ParseLength("640")
+	c.Assert(err, qt.IsNil)
+	c.Assert(l.px(), qt.Equals, 640.0)
+
+	_, err = ParseLength("3furlongs")
+	c.Assert(err, qt.ErrorMatches, `.*unknown unit "furlongs"`)
+
+	m, err := ParseMargins("1,2,3")
+	c.Assert(err, qt.IsNil)
+	c.Assert(m, qt.Equals, Margins{1, 2, 3, 2})
+}
+
+func TestViewport(t *testing.T) {
+	c := qt.New(t)
+
+	cc := CanvasCommon{Width: 9, Height: 5}
+	var drawn Extents
+	drawn.IncludeStroked(Pixel{-8, 0}, Pixel{64, 40})
+
+	// Default: sized to the text grid, ignoring what was drawn.
+	v := cc.Viewport(&Config{}, &drawn)
+	c.Assert(v.ViewBox, qt.Equals, Pixel{80, 90})
+	c.Assert(v.Origin, qt.Equals, Pixel{8, 12})
+
+	frame := Frame{
+		Tight:  true,
+		Margin: Margins{1, 1, 1, 1},
+		Width:  Length{50, "mm"},
+	}
+	v = cc.Viewport(&Config{Frame: &frame}, &drawn)
+	c.Assert(v.ViewBox, qt.Equals, Pixel{75, 43})
+	c.Assert(v.Origin, qt.Equals, Pixel{9.5, 1.5})
+	c.Assert(v.Height.String(), qt.Equals, "28.667mm")
+
+	frame = Frame{MaxWidth: Length{1, "in"}, Scale: 2}
+	v = cc.Viewport(&Config{Frame: &frame}, &drawn)
+	c.Assert(v.Width, qt.Equals, Length{1, "in"})
+	c.Assert(v.Height.String(), qt.Equals, "1.125in")
+}
@@ -0,0 +1,51 @@
+package svg
+
+// Geometry holds the dimensions, in CSS pixels, of the cell grid underlying the
+// SVG output, plus the sizes of the graphical elements drawn within cells.
+//
+// The default values suit a monospace font of 2:1 height:width ratio.
+// Fonts of other proportions cause text to drift away from the graphics,
+// unless the cell dimensions are adjusted to match.
+type Geometry struct {
+	// Advance width and line height of the font.
+	CellWidth, CellHeight float64
+
+	// Downward distance from the center of a cell to the baseline of the text
+	// it contains.
+	//     https://svgwg.org/svg2-draft/text.html#FontsGlyphs
+	BaselineOffset float64
+
+	// Radius of circles drawn for 'o', '*', '○' and '●'.
+	// If zero, each dialect applies its own default, proportional to CellWidth.
+	CircleRadius float64
+
+	// Scale factor applied to arrowheads, about their tip.
+	ArrowSize float64
+}
+
+var DefaultGeometry = Geometry{
+	CellWidth:      8,
+	CellHeight:     16,
+	BaselineOffset: 4,
+	CircleRadius:   0,
+	ArrowSize:      1,
+}
+
+// Geom returns the Geometry to be applied when drawing: c.Geometry if set,
+// otherwise DefaultGeometry.
+// X  Callers must not modify the referent.
+func (c *Config) Geom() *Geometry {
+	if c.Geometry == nil {
+		return &DefaultGeometry
+	}
+	return c.Geometry
+}
+
+// CircleRadiusOr returns g.CircleRadius, or if that is zero, 'dialectDefault'
+// in units of g.CellWidth.
+func (g *Geometry) CircleRadiusOr(dialectDefault float64) float64 {
+	if g.CircleRadius != 0 {
+		return g.CircleRadius
+	}
+	return dialectDefault * g.CellWidth
+}
@@ -0,0 +1,31 @@
+package svg
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestGeometry(t *testing.T) {
+	c := qt.New(t)
+
+	// Zero Config falls back to the 8x16 default.
+	var config Config
+	i := XyIndex{3, 2}
+	c.Assert(i.AsPixel(config.Geom()), qt.Equals, Pixel{24, 32})
+
+	// Odd dimensions yield fractional, not truncated, coordinates.
+	g := Geometry{CellWidth: 9, CellHeight: 21}
+	config.Geometry = &g
+	p := i.AsPixel(config.Geom())
+	c.Assert(p, qt.Equals, Pixel{27, 42})
+	p.Delta(Pixel{-g.CellWidth/2, g.CellHeight/2})
+	c.Assert(p.String(), qt.Equals, "22.5,52.5")
+
+	// Integral values print just as "%d" would.
+	c.Assert(Pixel{-4, 1e6}.String(), qt.Equals, "-4,1000000")
+
+	c.Assert(g.CircleRadiusOr(0.5), qt.Equals, 4.5)
+	g.CircleRadius = 3
+	c.Assert(g.CircleRadiusOr(0.5), qt.Equals, 3.0)
+}
@@ -0,0 +1,138 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blampe/goat/css"
+	"github.com/blampe/goat/internal"
+)
+
+// A rune drawn not as <text>, but as a <use> of an SVG <symbol> read from a file,
+// as bound by CSS properties "goat-glyph" and "goat-glyph-src".
+type glyph struct {
+	// Class names of the CSS rule(s) binding the glyph, applied to each <use>.
+	ClassNames []string
+
+	// Width and height in cells of the block covered by each occurrence, of which
+	// the cell containing the rune is at top-left.
+	cols, rows int
+
+	symbolID string
+	viewBox  string   // of the <svg> element read, if any
+	content  string   // children of the <svg> element read
+}
+
+// X  DRY with EmbedPrefix of cmd/goat.
+const embedPrefix = "embed:"
+
+// Interpret 'src', as found in the CSS file 'cssName', relative to the directory
+// of the latter.  An embedded CSS file may name only embedded glyphs.
+func resolveGlyphSrc(cssName, src string) string {
+	if strings.HasPrefix(src, embedPrefix) || cssName == "" {
+		return src
+	}
+	if after, found := strings.CutPrefix(cssName, embedPrefix); found {
+		return embedPrefix + path.Join(path.Dir(after), src)
+	}
+	if filepath.IsAbs(src) {
+		return src
+	}
+	return filepath.Join(filepath.Dir(cssName), src)
+}
+
+// Read the SVG file 'src', or the file of the css package's embedded FS named by
+// 'src' if prefixed by "embed:".
+func readGlyphSrc(src string) ([]byte, error) {
+	if after, found := strings.CutPrefix(src, embedPrefix); found {
+		return css.FileSystem.ReadFile(after)
+	}
+	return os.ReadFile(src)
+}
+
+var (
+	xmlProlog_re   = regexp.MustCompile(`(?s)<\?xml.*?\?>|<!DOCTYPE.*?>|<!--.*?-->`)
+	svgOpenTag_re  = regexp.MustCompile(`(?s)<svg\b[^>]*>`)
+	viewBoxAttr_re = regexp.MustCompile(`\bviewBox\s*=\s*["']([^"']*)["']`)
+	sizeAttr_re    = regexp.MustCompile(`\b(width|height)\s*=\s*["']([0-9.]+)(?:px)?["']`)
+)
+
+// Parse the content of an SVG file, for use as the content of a <symbol>.
+func newGlyph(src string, bytes []byte) (*glyph, error) {
+	s := xmlProlog_re.ReplaceAllString(string(bytes), "")
+	loc := svgOpenTag_re.FindStringIndex(s)
+	end := strings.LastIndex(s, "</svg>")
+	if loc == nil || end < loc[1] {
+		return nil, fmt.Errorf("%s: no <svg> element found", src)
+	}
+	gl := glyph{
+		content: strings.TrimSpace(s[loc[1]:end]),
+	}
+	openTag := s[loc[0]:loc[1]]
+	if m := viewBoxAttr_re.FindStringSubmatch(openTag); m != nil {
+		gl.viewBox = m[1]
+	} else {
+		// Synthesize a viewBox from the dimensions, if known.
+		size := make(map[string]string)
+		for _, m := range sizeAttr_re.FindAllStringSubmatch(openTag, -1) {
+			size[m[1]] = m[2]
+		}
+		if len(size) == 2 {
+			gl.viewBox = "0 0 " + size["width"] + " " + size["height"]
+		}
+	}
+	if len(gl.viewBox) == 0 {
+		return nil, fmt.Errorf("%s: <svg> element has neither viewBox, nor width and height",
+			src)
+	}
+	return &gl, nil
+}
+
+// Parse a "goat-glyph-size" value "COLSxROWS", e.g. "3x2".
+func parseGlyphSize(s string) (cols, rows int, err error) {
+	c, r, found := strings.Cut(s, "x")
+	if found {
+		cols, err = strconv.Atoi(c)
+		if err == nil {
+			rows, err = strconv.Atoi(r)
+		}
+	}
+	if !found || err != nil || cols < 1 || rows < 1 {
+		return 0, 0, fmt.Errorf("invalid %s %q: expected COLSxROWS e.g. \"3x2\"",
+			goat_glyph_size, s)
+	}
+	return
+}
+
+// Draw 'gl' over the block of cells of which 'i' is the top-left.
+//
+// X  Each <symbol> is written just before its first <use>, on the assumption that
+//    the drawing of text occurs once only.
+func (gl *glyph) draw(out io.Writer, g *Geometry, i XyIndex, defined map[*glyph]bool) {
+	if !defined[gl] {
+		internal.MustFPrintf(out, `    <symbol id="%s" viewBox="%s">%s</symbol>
+`,
+			gl.symbolID, gl.viewBox, gl.content)
+		defined[gl] = true
+	}
+	p := i.AsPixel(g)
+	topLeft := Pixel{p.X - g.CellWidth/2, p.Y - g.CellHeight/2}
+	size := Pixel{float64(gl.cols) * g.CellWidth, float64(gl.rows) * g.CellHeight}
+	internal.MustFPrintf(out,
+		`    <use %s x="%s" y="%s" width="%s" height="%s" class="%s"%s/>
+`,
+		useHref(out, gl.symbolID),
+		coord(topLeft.X), coord(topLeft.Y),
+		coord(size.X), coord(size.Y),
+		strings.Join(gl.ClassNames, " "),
+		sourceAttrs(out, SourceCell(i)))
+	extend(out, func(e *Extents) {
+		e.Include(topLeft, topLeft.Sum(size))
+	})
+}
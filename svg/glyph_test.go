@@ -0,0 +1,83 @@
+package svg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/blampe/goat"
+)
+
+func TestNewGlyph(t *testing.T) {
+	c := qt.New(t)
+
+	gl, err := newGlyph("a.svg", []byte(`<?xml version="1.0"?>
+<!-- comment <svg> -->
+<svg xmlns="http://www.w3.org/2000/svg" width="24" height="12px"><rect width="24" height="12"/></svg>
+`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(gl.viewBox, qt.Equals, "0 0 24 12")
+	c.Assert(gl.content, qt.Equals, `<rect width="24" height="12"/>`)
+
+	_, err = newGlyph("b.svg", []byte(`<svg><rect/></svg>`))
+	c.Assert(err, qt.ErrorMatches, `b.svg: <svg> element has neither viewBox.*`)
+}
+
+func TestGlyphBinding(t *testing.T) {
+	c := qt.New(t)
+	dialectSet := goat.MakeRuneSet('-', ' ')
+
+	bindings := make(MarkBindingMap)
+	err := ParseCss(bindings, []byte(`
+.db { goat-glyph: "D"; goat-glyph-src: "embed:icons/database.svg"; goat-glyph-size: "2x1"; }
+`))
+	c.Assert(err, qt.IsNil)
+	config, err := NewConfig(dialectSet, bindings)
+	c.Assert(err, qt.IsNil)
+
+	gl := config.glyphs['D']
+	c.Assert(gl, qt.IsNotNil)
+	c.Assert(gl.ClassNames, qt.DeepEquals, []string{"db"})
+
+	var out bytes.Buffer
+	defined := make(map[*glyph]bool)
+	gl.draw(&out, &DefaultGeometry, XyIndex{1, 1}, defined)
+	gl.draw(&out, &DefaultGeometry, XyIndex{4, 1}, defined)
+	c.Assert(bytes.Count(out.Bytes(), []byte("<symbol ")), qt.Equals, 1)
+	c.Assert(string(uniqueIDs(out.Bytes())), qt.Matches, `(?s).*`+
+		`<use href="#goat-[0-9a-f]{8}-glyph-44" xlink:href="#goat-[0-9a-f]{8}-glyph-44" `+
+		`x="4" y="8" width="16" height="16" class="db"/>.*`)
+
+	err = ParseCss(make(MarkBindingMap), []byte(`.x { goat-glyph: "D"; }`))
+	c.Assert(err, qt.ErrorMatches, `.*contained "goat-glyph", but no "goat-glyph-src".`)
+
+	bindings = make(MarkBindingMap)
+	err = ParseCss(bindings, []byte(`.x { goat-glyph: "-"; goat-glyph-src: "x.svg"; }`))
+	c.Assert(err, qt.IsNil)
+	_, err = NewConfig(dialectSet, bindings)
+	c.Assert(err, qt.ErrorMatches, `(?s).*reserved rune '-' .*`)
+}
+
+func TestGlyphSrcRelativeToCSS(t *testing.T) {
+	c := qt.New(t)
+	dir := t.TempDir()
+	c.Assert(os.Mkdir(filepath.Join(dir, "icons"), 0o755), qt.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "icons", "g.svg"),
+		[]byte(`<svg viewBox="0 0 8 16"><circle r="4"/></svg>`), 0o644), qt.IsNil)
+
+	bindings := make(MarkBindingMap)
+	err := ParseCssFile(bindings, filepath.Join(dir, "theme.css"),
+		[]byte(`.g { goat-glyph: "G"; goat-glyph-src: "icons/g.svg"; }`))
+	c.Assert(err, qt.IsNil)
+	config, err := NewConfig(goat.MakeRuneSet('-', ' '), bindings)
+	c.Assert(err, qt.IsNil)
+	c.Assert(config.glyphs['G'].content, qt.Equals, `<circle r="4"/>`)
+
+	c.Assert(resolveGlyphSrc("", "icons/g.svg"), qt.Equals, "icons/g.svg")
+	c.Assert(resolveGlyphSrc("embed:palette/x.css", "../icons/d.svg"), qt.Equals, "embed:icons/d.svg")
+	c.Assert(resolveGlyphSrc("docs/x.css", "embed:icons/d.svg"), qt.Equals, "embed:icons/d.svg")
+	c.Assert(resolveGlyphSrc("docs/x.css", "/abs/d.svg"), qt.Equals, "/abs/d.svg")
+}
@@ -0,0 +1,134 @@
+package svg
+
+import (
+	"github.com/blampe/goat"
+)
+
+// X  CanvasCommon stores its cells densely, in row-major order, rather than in
+//    maps keyed by XyIndex: classification looks up several neighbors of each cell.
+
+// Stands in 'cells' for a position lying beyond the end of its line.
+const absent = rune(0)
+
+// One bit per cell, in row-major order.
+type bitmap []uint64
+
+func newBitmap(n int) bitmap {
+	return make(bitmap, (n+63)/64)
+}
+
+func (b bitmap) get(n int) bool {
+	return n/64 < len(b) && b[n/64]&(1<<(n%64)) != 0
+}
+
+func (b bitmap) set(n int) {
+	b[n/64] |= 1 << (n % 64)
+}
+
+// Returns the offset of 'i' within 'c.cells', and whether 'i' lies on the canvas at all.
+func (c *CanvasCommon) offsetOf(i XyIndex) (int, bool) {
+	if i.X < 0 || i.X >= c.Width || i.Y < 0 || i.Y >= c.Height {
+		return 0, false
+	}
+	return i.Y*c.Width + i.X, true
+}
+
+// Returns the rune at 'i' if not classified as text, with 'found' false if 'i' lies
+// beyond the end of its line, or off the canvas entirely.
+func (c *CanvasCommon) DataRuneAt(i XyIndex) (r rune, found bool) {
+	n, ok := c.offsetOf(i)
+	if !ok || c.cells[n] == absent || c.isText.get(n) {
+		return 0, false
+	}
+	if c.isJoint.get(n) {
+		return c.jointAs, true
+	}
+	return c.cells[n], true
+}
+
+// Returns the rune at 'i' if classified as text.
+func (c *CanvasCommon) TextRuneAt(i XyIndex) (r rune, found bool) {
+	n, ok := c.offsetOf(i)
+	if !ok || !c.isText.get(n) {
+		return 0, false
+	}
+	if r = c.cells[n]; r == absent {
+		// X  Text may be found beyond the end of a line, where classification
+		//    saw only the ' ' returned by RuneAt().
+		r = ' '
+	}
+	return r, true
+}
+
+// MarkJoints has each cell holding a rune of 'runes' seen by RuneAt() and DataRuneAt()
+// as 'as', a joint the dialect draws as graphics.  To be called before MoveToText().
+// The cells keep the runes written, as seen by TextRuneAt() and WrittenRuneAt().
+func (c *CanvasCommon) MarkJoints(runes goat.RuneSet, as rune) {
+	if len(runes) == 0 {
+		return
+	}
+	c.jointAs = as
+	c.isJoint = newBitmap(len(c.cells))
+	for n, r := range c.cells {
+		if runes.Contains(r) {
+			c.isJoint.set(n)
+		}
+	}
+}
+
+// WrittenRuneAt returns the rune written at 'i' in the input, whether text or
+// graphics, and whatever its role: a user-defined joint is returned as such, rather
+// than as the dialect's own joint.  Returns ' ' if none, as for the second cell of a
+// wide character.
+func (c *CanvasCommon) WrittenRuneAt(i XyIndex) rune {
+	n, ok := c.offsetOf(i)
+	if !ok || c.cells[n] == absent || c.cells[n] == wideTail {
+		return ' '
+	}
+	return c.cells[n]
+}
+
+// Reports whether the rune at 'i', if not classified as text, belongs to 'set'.
+func (c *CanvasCommon) DataInSet(set goat.RuneSet, i XyIndex) bool {
+	r, found := c.DataRuneAt(i)
+	return found && set.Contains(r)
+}
+
+// Data returns a copy of the runes not classified as text, as once held by a field of
+// that name.
+//
+// Deprecated: use DataRuneAt(), which neither copies nor allocates.
+func (c *CanvasCommon) Data() map[XyIndex]rune {
+	m := make(map[XyIndex]rune)
+	for i := range LeftRightMinor(c.Width, c.Height) {
+		if r, found := c.DataRuneAt(i); found {
+			m[i] = r
+		}
+	}
+	return m
+}
+
+// TextRunes returns a copy of the runes classified as text, as once held by a field
+// of that name.
+//
+// Deprecated: use TextRuneAt(), which neither copies nor allocates.
+func (c *CanvasCommon) TextRunes() map[XyIndex]rune {
+	m := make(map[XyIndex]rune)
+	for i := range LeftRightMinor(c.Width, c.Height) {
+		if r, found := c.TextRuneAt(i); found {
+			m[i] = r
+		}
+	}
+	return m
+}
+
+// Arg 'canvasMap' is typically that returned by CanvasCommon.Data() or TextRunes().
+//
+// Deprecated: use CanvasCommon.DataInSet().
+func InSet(set goat.RuneSet, canvasMap map[XyIndex]rune, i XyIndex) bool {
+	r, inMap := canvasMap[i]
+	if !inMap {
+		return false 	// r == rune(0)
+	}
+	return set.Contains(r)
+}
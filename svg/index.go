@@ -1,33 +1,26 @@
 package svg
 
 import (
-	"github.com/blampe/goat"
+	"strconv"
 )
 
 // XyIndex represents a position within an ASCII diagram, and
 // of the visual center of a corresponding rectangle within the output SVG,
-// where both have a height:width ratio of 2:1.
+// of dimensions given by Geometry.
 type XyIndex struct {
 	// units of cells
 	X, Y int
 }
 
 
-// Arg 'canvasMap' is typically either Canvas.data or Canvas.text
-func InSet(set goat.RuneSet, canvasMap map[XyIndex]rune, i XyIndex) bool {
-	r, inMap := canvasMap[i]
-	if !inMap {
-		return false 	// r == rune(0)
-	}
-	return set.Contains(r)
-}
-
 
 // Type "pixel' represents the CSS-pixel coordinates of the apparent visual center of
-// an 8x16 cell pointed to by an XyIndex.
+// a cell pointed to by an XyIndex.
+//   X  Float rather than int, because cell dimensions from Geometry need not be
+//      even numbers -- halving them would otherwise lose precision.
 type Pixel struct {
 	// units of CSS "pixels"
-	X, Y int
+	X, Y float64
 }
 
 func (a *Pixel) Delta(b Pixel) {
@@ -42,23 +35,37 @@ func (a Pixel) Sum(b Pixel) Pixel {
 	}
 }
 
-const (
-	CellWidth = 8
-	CellHeight = 16
-	W = CellWidth
-	H = CellHeight
-)
+// Formats as an SVG coordinate pair "X,Y".
+func (a Pixel) String() string {
+	return coord(a.X) + "," + coord(a.Y)
+}
 
+// Appends 'a' to 'b' as formatted by String().
+func (a Pixel) appendTo(b []byte) []byte {
+	b = appendCoord(b, a.X)
+	b = append(b, ',')
+	return appendCoord(b, a.Y)
+}
+
+// Shortest decimal representation, never in exponent form, of a coordinate or length.
+// Integral values print exactly as "%d" would.
+func coord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Appends 'v' to 'b' as formatted by coord().
+func appendCoord(b []byte, v float64) []byte {
+	return strconv.AppendFloat(b, v, 'f', -1, 64)
+}
 
-func (i *XyIndex) AsPixel() Pixel {
-	// TODO  define constants rather than hard-wire width and height of cell
+func (i *XyIndex) AsPixel(g *Geometry) Pixel {
 	return Pixel{
-		X: i.X * CellWidth,
-		Y: i.Y * CellHeight}
+		X: float64(i.X) * g.CellWidth,
+		Y: float64(i.Y) * g.CellHeight}
 }
 
-func (i *XyIndex) AsPixelXY() (int, int) {
-	p := i.AsPixel()
+func (i *XyIndex) AsPixelXY(g *Geometry) (float64, float64) {
+	p := i.AsPixel(g)
 	return p.X, p.Y
 }
 
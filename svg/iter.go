@@ -1,71 +1,63 @@
 package svg
 
-// XX  Simplify to closure functions rather than channels?
-//      X Callers currently use 'range' -- rewrite would be required.
-// XX  Use stdlib package "iter"?
-type CanvasIterator func(width int, height int) chan XyIndex
+import (
+	"iter"
+)
 
-func UpDownMinor(width int, height int) chan XyIndex {
-	c := make(chan XyIndex)
-	go func() {
+// CanvasIterator yields every XyIndex of a canvas of the given dimensions exactly once,
+// in an order particular to the iterator.  Callers may stop early.
+type CanvasIterator func(width int, height int) iter.Seq[XyIndex]
+
+func UpDownMinor(width int, height int) iter.Seq[XyIndex] {
+	return func(yield func(XyIndex) bool) {
 		for w := 0; w < width; w++ {
 			for h := 0; h < height; h++ {
-				c <- XyIndex{w, h}
+				if !yield(XyIndex{w, h}) {
+					return
+				}
 			}
 		}
-		close(c)
-	}()
-	return c
+	}
 }
 
-func LeftRightMinor(width int, height int) chan XyIndex {
-	c := make(chan XyIndex)
-	go func() {
+func LeftRightMinor(width int, height int) iter.Seq[XyIndex] {
+	return func(yield func(XyIndex) bool) {
 		for h := 0; h < height; h++ {
 			for w := 0; w < width; w++ {
-				c <- XyIndex{w, h}
+				if !yield(XyIndex{w, h}) {
+					return
+				}
 			}
 		}
-		close(c)
-	}()
-	return c
+	}
 }
 
-func DiagDown(width int, height int) chan XyIndex {
-	c := make(chan XyIndex)
-	go func() {
-		minSum := -height + 1
-		maxSum := width
-
-		for sum := minSum; sum <= maxSum; sum++ {
-			for w := 0; w < width; w++ {
-				for h := 0; h < height; h++ {
-					if w-h == sum {
-						c <- XyIndex{w, h}
-					}
+// Diagonals running down and to the right, from the bottom-left corner of the canvas
+// to the top-right; each diagonal from its top-left end.
+func DiagDown(width int, height int) iter.Seq[XyIndex] {
+	return func(yield func(XyIndex) bool) {
+		// X  Along each diagonal, w-h is constant.
+		for diff := -height + 1; diff < width; diff++ {
+			for w := max(0, diff); w < min(width, height+diff); w++ {
+				if !yield(XyIndex{w, w - diff}) {
+					return
 				}
 			}
 		}
-		close(c)
-	}()
-	return c
+	}
 }
 
-func DiagUp(width int, height int) chan XyIndex {
-	c := make(chan XyIndex)
-	go func() {
-		maxSum := width + height - 2
-
-		for sum := 0; sum <= maxSum; sum++ {
-			for w := 0; w < width; w++ {
-				for h := 0; h < height; h++ {
-					if h+w == sum {
-						c <- XyIndex{w, h}
-					}
+// Diagonals running up and to the right, from the top-left corner of the canvas
+// to the bottom-right; each diagonal from its bottom-left end.
+func DiagUp(width int, height int) iter.Seq[XyIndex] {
+	return func(yield func(XyIndex) bool) {
+		// X  Along each diagonal, w+h is constant.
+		for sum := 0; sum <= width+height-2; sum++ {
+			for w := max(0, sum-height+1); w <= min(width-1, sum); w++ {
+				if !yield(XyIndex{w, sum - w}) {
+					return
 				}
 			}
 		}
-		close(c)
-	}()
-	return c
+	}
 }
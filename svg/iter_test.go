@@ -1,6 +1,8 @@
 package svg
 
 import (
+	"iter"
+	"slices"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
@@ -17,7 +19,7 @@ func TestIterators(t *testing.T) {
 	c := qt.New(t)
 
 	tests := []struct {
-		iterator chan XyIndex
+		iterator iter.Seq[XyIndex]
 		expected []XyIndex
 	}{
 		// UpDown
@@ -88,3 +90,52 @@ func TestIterators(t *testing.T) {
 		c.Assert(result, eq, tt.expected)
 	}
 }
+
+// Each iterator must visit every cell exactly once, whatever the shape of the canvas,
+// and must permit stopping early.
+func TestIteratorsCoverage(t *testing.T) {
+	c := qt.New(t)
+
+	for _, ci := range []CanvasIterator{UpDownMinor, LeftRightMinor, DiagUp, DiagDown} {
+		for _, dims := range [][2]int{{0, 0}, {1, 1}, {1, 5}, {5, 1}, {4, 7}, {7, 4}} {
+			width, height := dims[0], dims[1]
+			seen := make(map[XyIndex]int)
+			for i := range ci(width, height) {
+				c.Assert(i.X >= 0 && i.X < width && i.Y >= 0 && i.Y < height, qt.IsTrue)
+				seen[i]++
+			}
+			c.Assert(seen, qt.HasLen, width*height)
+			for _, n := range seen {
+				c.Assert(n, qt.Equals, 1)
+			}
+		}
+		first := slices.Collect(func(yield func(XyIndex) bool) {
+			for i := range ci(3, 3) {
+				if !yield(i) {
+					return
+				}
+				break
+			}
+		})
+		c.Assert(first, qt.HasLen, 1)
+	}
+}
+
+func BenchmarkIterators(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		ci   CanvasIterator
+	}{
+		{"UpDownMinor", UpDownMinor},
+		{"LeftRightMinor", LeftRightMinor},
+		{"DiagUp", DiagUp},
+		{"DiagDown", DiagDown},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for range bm.ci(120, 80) {
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package svg
+
+import (
+	"io"
+)
+
+// A Linter is an AbstractCanvas able to report constructs of its dialect that are
+// valid input, but likely mistakes.
+type Linter interface {
+	Lint(config *Config)
+}
+
+// Lint records in config.Diagnostics, without drawing, the suspicious constructs of
+// the diagram of 'ac': trailing whitespace, rows wider than 'maxWidth' runes if
+// non-zero, and the problems of marks found when drawing text; then those of the
+// dialect, if 'ac' is a Linter.
+func Lint(config *Config, ac AbstractCanvas, maxWidth int) {
+	cc := ac.GetCommon()
+	for y := 0; y < cc.Height; y++ {
+		// Cells past the end of a line shorter than the widest are 'absent'.
+		end := cc.Width
+		for end > 0 && cc.cells[y*cc.Width+end-1] == absent {
+			end--
+		}
+		spaces := end
+		for spaces > 0 && cc.cells[y*cc.Width+spaces-1] == ' ' {
+			spaces--
+		}
+		if spaces < end {
+			cc.Diagnose(config, XyIndex{spaces, y}, SeverityWarning, CodeTrailingSpace,
+				"trailing whitespace")
+		}
+		if maxWidth > 0 && end > maxWidth {
+			cc.Diagnose(config, XyIndex{maxWidth, y}, SeverityWarning, CodeRowTooWide,
+				"row is %d characters wide, exceeding the limit of %d", end, maxWidth)
+		}
+	}
+	Writetext(io.Discard, config, ac)
+	if linter, ok := ac.(Linter); ok {
+		linter.Lint(config)
+	}
+}
@@ -0,0 +1,282 @@
+package svg
+
+import (
+	"cmp"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/blampe/goat/internal"
+)
+
+// A straight segment or an elliptical arc, as drawn by WritePolyline(),
+// RoundedCorner.DrawCentered() or Bridge.Draw().
+type stroke struct {
+	from, to Pixel
+
+	// Zero for a straight segment.
+	radius Pixel
+	sweepFlag int
+
+	// Cells of the input diagram from which drawn.
+	src Source
+}
+
+func (s stroke) isArc() bool {
+	return s.radius != Pixel{}
+}
+
+func (s stroke) reversed() stroke {
+	r := s
+	r.from, r.to = s.to, s.from
+	r.sweepFlag = 1 - s.sweepFlag
+	r.src = s.src.reversed()
+	return r
+}
+
+// Direction of travel from 'from' to 'to', as a unit vector; meaningful only for
+// straight segments.
+func (s stroke) direction() Pixel {
+	dx, dy := s.to.X-s.from.X, s.to.Y-s.from.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return Pixel{}
+	}
+	return Pixel{dx / length, dy / length}
+}
+
+// Record 's' for later merging, if 'out' is collecting strokes.  Returns false
+// if the caller should instead write 's' out as an element of its own.
+func record(out io.Writer, s stroke) bool {
+	d, ok := out.(*drawing)
+	if !ok || !d.mergeStrokes {
+		return false
+	}
+	d.strokes = append(d.strokes, s)
+	return true
+}
+
+// Endpoints closer than this are regarded as joined.
+const joinTolerance = 0.01
+
+type vertexKey struct{ x, y int64 }
+
+func keyOf(p Pixel) vertexKey {
+	return vertexKey{
+		int64(math.Round(p.X / joinTolerance)),
+		int64(math.Round(p.Y / joinTolerance)),
+	}
+}
+
+// splitAtJoints returns 'strokes' with each straight segment split at any endpoint
+// of another stroke lying within it, and then any segment duplicating another dropped.
+//
+// X  The dialects lengthen or shorten lines by fixed amounts, e.g. by NeedsNudgingLeft
+//    of ascii/a_line.go, to meet a corner or joint; and draw some spans twice over,
+//    e.g. as both a line and a half-step.  Drawn as elements of their own, the
+//    overlaps are unseen with an opaque stroke; merged, they would double back on
+//    themselves, and a line ending within another would not join it.
+func splitAtJoints(strokes []stroke) (split []stroke) {
+	var vertices []Pixel
+	seenVertex := make(map[vertexKey]bool)
+	for _, s := range strokes {
+		for _, p := range []Pixel{s.from, s.to} {
+			if !seenVertex[keyOf(p)] {
+				seenVertex[keyOf(p)] = true
+				vertices = append(vertices, p)
+			}
+		}
+	}
+	slices.SortFunc(vertices, func(a, b Pixel) int { return cmp.Compare(a.X, b.X) })
+
+	type segmentKey struct{ a, b vertexKey }
+	seenSegment := make(map[segmentKey]bool)
+	add := func(s stroke) {
+		if !s.isArc() {
+			a, b := keyOf(s.from), keyOf(s.to)
+			if a == b {
+				return
+			}
+			if b.x < a.x || (b.x == a.x && b.y < a.y) {
+				a, b = b, a
+			}
+			if seenSegment[segmentKey{a, b}] {
+				return
+			}
+			seenSegment[segmentKey{a, b}] = true
+		}
+		split = append(split, s)
+	}
+	for _, s := range strokes {
+		if s.isArc() {
+			add(s)
+			continue
+		}
+		dx, dy := s.to.X-s.from.X, s.to.Y-s.from.Y
+		length := math.Hypot(dx, dy)
+		// Distances along 's' of the vertices within it.
+		var cuts []float64
+		lo, hi := min(s.from.X, s.to.X)-joinTolerance, max(s.from.X, s.to.X)+joinTolerance
+		i, _ := slices.BinarySearchFunc(vertices, lo, func(p Pixel, x float64) int {
+			return cmp.Compare(p.X, x)
+		})
+		for ; i < len(vertices) && vertices[i].X <= hi; i++ {
+			p := vertices[i]
+			px, py := p.X-s.from.X, p.Y-s.from.Y
+			if math.Abs(px*dy-py*dx)/length > joinTolerance {
+				continue
+			}
+			if t := (px*dx + py*dy) / length; t > joinTolerance && t < length-joinTolerance {
+				cuts = append(cuts, t)
+			}
+		}
+		slices.Sort(cuts)
+		from := s.from
+		for _, t := range cuts {
+			to := Pixel{s.from.X + dx*t/length, s.from.Y + dy*t/length}
+			add(stroke{from: from, to: to, src: s.src})
+			from = to
+		}
+		add(stroke{from: from, to: s.to, src: s.src})
+	}
+	return
+}
+
+// mergeStrokes partitions 'strokes' into chains, each continuous from one end to
+// the other, by walking the graph in which strokes are edges and their endpoints
+// vertices.  At a vertex joining more than two strokes, the walk prefers to continue
+// straight ahead, so that crossing lines remain unbroken.
+//
+// Output order depends only on the order of 'strokes'.
+func mergeStrokes(strokes []stroke) (chains [][]stroke) {
+	strokes = splitAtJoints(strokes)
+	incident := make(map[vertexKey][]int)
+	for i, s := range strokes {
+		incident[keyOf(s.from)] = append(incident[keyOf(s.from)], i)
+		incident[keyOf(s.to)] = append(incident[keyOf(s.to)], i)
+	}
+	used := make([]bool, len(strokes))
+	unusedDegree := func(k vertexKey) (n int) {
+		for _, i := range incident[k] {
+			if !used[i] {
+				n++
+			}
+		}
+		return
+	}
+
+	// Extend 'chain' forward from its last point, as far as possible.
+	extend := func(chain []stroke) []stroke {
+		for {
+			last := chain[len(chain)-1]
+			at := keyOf(last.to)
+			heading := last.direction()
+			best, bestScore := -1, math.Inf(-1)
+			for _, i := range incident[at] {
+				if used[i] {
+					continue
+				}
+				next := strokes[i]
+				if keyOf(next.from) != at {
+					next = next.reversed()
+				}
+				score := 0.0
+				if !last.isArc() && !next.isArc() {
+					d := next.direction()
+					score = heading.X*d.X + heading.Y*d.Y
+				}
+				if score > bestScore {
+					best, bestScore = i, score
+				}
+			}
+			if best < 0 {
+				return chain
+			}
+			used[best] = true
+			next := strokes[best]
+			if keyOf(next.from) != at {
+				next = next.reversed()
+			}
+			chain = append(chain, next)
+		}
+	}
+
+	// Chains begin preferably at vertices of odd degree -- dangling ends, or
+	// T-junctions -- so that as few chains as possible result.
+	for pass := 0; pass < 2; pass++ {
+		for i, s := range strokes {
+			if used[i] {
+				continue
+			}
+			if pass == 0 {
+				switch {
+				case unusedDegree(keyOf(s.from))%2 == 1:
+				case unusedDegree(keyOf(s.to))%2 == 1:
+					s = s.reversed()
+				default:
+					continue
+				}
+			}
+			used[i] = true
+			chains = append(chains, extend([]stroke{s}))
+		}
+	}
+	return
+}
+
+// Append 'chain' to 'b' as SVG path data, coalescing collinear consecutive segments.
+// A chain returning to its beginning is closed with "Z", for a true join there too.
+func appendPathData(b []byte, chain []stroke) []byte {
+	last := len(chain) - 1
+	closed := last > 0 && keyOf(chain[last].to) == keyOf(chain[0].from)
+
+	b = append(b, "M "...)
+	b = chain[0].from.appendTo(b)
+	for i := 0; i <= last; i++ {
+		s := chain[i]
+		if s.isArc() {
+			b = append(b, " A "...)
+			b = s.radius.appendTo(b)
+			b = append(b, " 0 0,"...)
+			b = appendCoord(b, float64(s.sweepFlag))
+			b = append(b, ' ')
+			b = s.to.appendTo(b)
+			if closed && i == last {
+				b = append(b, " Z"...)
+			}
+			continue
+		}
+		for i < last && !chain[i+1].isArc() && collinear(s, chain[i+1]) {
+			i++
+			s.to = chain[i].to
+		}
+		if closed && i == last {
+			b = append(b, " Z"...)
+			break
+		}
+		b = append(b, " L "...)
+		b = s.to.appendTo(b)
+	}
+	return b
+}
+
+func collinear(a, b stroke) bool {
+	da, db := a.direction(), b.direction()
+	return math.Abs(da.X*db.Y-da.Y*db.X) < 1e-9 && da.X*db.X+da.Y*db.Y > 0
+}
+
+// Write each chain of the strokes collected by 'body' as a single <path> element.
+func writeMergedStrokes(out io.Writer, body *drawing) {
+	internal.MustFPrintf(out, "  <g id='%s'>\n", "paths")
+	var b []byte
+	for _, chain := range mergeStrokes(body.strokes) {
+		src := SourceLine(chain[0].src.Start, chain[len(chain)-1].src.Stop)
+		b = append(b[:0], `    <path class="path" d="`...)
+		b = appendPathData(b, chain)
+		b = append(b, '"')
+		b = appendSourceAttrs(b, body, src)
+		b = append(b, "></path>\n"...)
+		writeElement(out, b)
+	}
+	internal.MustFPrintf(out, "  </g>\n")
+}
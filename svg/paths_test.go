@@ -0,0 +1,58 @@
+package svg
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMergeStrokes(t *testing.T) {
+	c := qt.New(t)
+
+	d := &drawing{mergeStrokes: true}
+	// A cross, drawn as four segments meeting at {16,16}, out of order.
+	WritePolyline(d, Pixel{16, 16}, Pixel{32, 16}, Source{})
+	WritePolyline(d, Pixel{16, 0}, Pixel{16, 16}, Source{})
+	WritePolyline(d, Pixel{0, 16}, Pixel{16, 16}, Source{})
+	WritePolyline(d, Pixel{16, 16}, Pixel{16, 32}, Source{})
+	// A rounded corner continuing a dangling line.
+	WritePolyline(d, Pixel{48, 0}, Pixel{48, 24}, Source{})
+	rc := RoundedCorner{Orientation: O_SW}
+	rc.DrawCentered(d, Pixel{56, 24}, Pixel{8, 8})
+	c.Assert(d.Len(), qt.Equals, 0)
+
+	var out bytes.Buffer
+	writeMergedStrokes(&out, d)
+	c.Assert(out.String(), qt.Equals, `  <g id='paths'>
+    <path class="path" d="M 32,16 L 0,16"></path>
+    <path class="path" d="M 16,0 L 16,32"></path>
+    <path class="path" d="M 48,0 L 48,24 A 8,8 0 0,0 56,32"></path>
+  </g>
+`)
+}
+
+func TestMergeStrokesOverlapping(t *testing.T) {
+	c := qt.New(t)
+
+	d := &drawing{mergeStrokes: true}
+	// As drawn from "|_|": each '|' both as a line and as a half-step overlapping it,
+	// and the '_' nudged to meet them.
+	WritePolyline(d, Pixel{0, 8}, Pixel{16, 8}, Source{})
+	WritePolyline(d, Pixel{0, -8}, Pixel{0, 8}, Source{})
+	WritePolyline(d, Pixel{16, -8}, Pixel{16, 8}, Source{})
+	WritePolyline(d, Pixel{0, 0}, Pixel{0, 8}, Source{})
+	WritePolyline(d, Pixel{16, 0}, Pixel{16, 8}, Source{})
+	// A line ending within another.
+	WritePolyline(d, Pixel{32, 0}, Pixel{64, 0}, Source{})
+	WritePolyline(d, Pixel{48, 0}, Pixel{48, 16}, Source{})
+
+	var out bytes.Buffer
+	writeMergedStrokes(&out, d)
+	c.Assert(out.String(), qt.Equals, `  <g id='paths'>
+    <path class="path" d="M 0,-8 L 0,8 L 16,8 L 16,-8"></path>
+    <path class="path" d="M 32,0 L 64,0"></path>
+    <path class="path" d="M 48,0 L 48,16"></path>
+  </g>
+`)
+}
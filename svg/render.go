@@ -0,0 +1,79 @@
+package svg
+
+import (
+	"io"
+
+	"github.com/blampe/goat/internal"
+)
+
+// A CSSFile is included in the SVG output within a <style> element titled by 'Name',
+// after the default stylesheet.  Its rules may also bind marks and glyphs, as
+// described by ParseCssFile(), a relative goat-glyph-src being read relative to the
+// directory of 'Name'.
+type CSSFile struct {
+	Name    string
+	Content []byte
+}
+
+// RenderOptions controls Render().  The zero value draws an ASCII diagram with the
+// default stylesheet, black on a light background and white on a dark one.
+type RenderOptions struct {
+	// Name of a registered dialect, by default "ascii".
+	Dialect string
+
+	// Colors of the default stylesheet, by default "#000" and "#FFF".
+	LightScheme, DarkScheme string
+	OmitDefaultCSS          bool
+
+	CSS []CSSFile
+
+	// Sets Config.ScopeCSS, as wanted of an <svg> element inlined in HTML.
+	ScopeCSS bool
+
+	// If non-nil, called to adjust the Config just before the diagram is read,
+	// e.g. to set Geometry or Frame.
+	Configure func(*Config)
+}
+
+// Render reads a diagram from 'in', writing to 'out' a complete <svg> element.
+// Problems found are returned as Diagnostics joined by errors.Join(); a stylesheet
+// in error prevents any output.
+//
+// X  The dialect's package must have been imported, to register it.
+func Render(out io.Writer, in io.Reader, opts RenderOptions) error {
+	name := opts.Dialect
+	if len(name) == 0 {
+		name = "ascii"
+	}
+	dialect, err := LookupDialect(name)
+	if err != nil {
+		return err
+	}
+	markBindingMap := make(MarkBindingMap)
+	var cssInclude []internal.NamedReadSeeker
+	for _, f := range opts.CSS {
+		if err := ParseCssFile(markBindingMap, f.Name, f.Content); err != nil {
+			return err
+		}
+		cssInclude = append(cssInclude, internal.NewNamedBytesReader(f.Content, f.Name))
+	}
+	config, err := NewConfig(dialect.ReservedSet, markBindingMap)
+	if err != nil {
+		return err
+	}
+	config.ScopeCSS = opts.ScopeCSS
+	if opts.Configure != nil {
+		opts.Configure(&config)
+	}
+	light, dark := opts.LightScheme, opts.DarkScheme
+	if len(light) == 0 {
+		light = "#000"
+	}
+	if len(dark) == 0 {
+		dark = "#FFF"
+	}
+	canvas := dialect.NewCanvas(&config, in)
+	WriteCanvas(&config, canvas,
+		!opts.OmitDefaultCSS, ColorsOnlyCssFileContent(light, dark), cssInclude, out)
+	return config.Err()
+}
@@ -0,0 +1,59 @@
+package svg
+
+import (
+	"io"
+	"strconv"
+)
+
+// Source locates in the input diagram the cells from which an SVG element was drawn:
+// for a line its two ends, otherwise the single cell 'Start'.
+type Source struct {
+	Start, Stop XyIndex
+	isLine      bool
+}
+
+func SourceCell(i XyIndex) Source {
+	return Source{Start: i, Stop: i}
+}
+
+func SourceLine(start, stop XyIndex) Source {
+	return Source{Start: start, Stop: stop, isLine: true}
+}
+
+func (s Source) reversed() Source {
+	s.Start, s.Stop = s.Stop, s.Start
+	return s
+}
+
+// Returns the attributes locating 'src' in the input, each preceded by a space, if
+// 'out' is collecting them; otherwise the empty string.
+//
+// X  Rows and columns are numbered from 1 as by a text editor, and counted
+//    from the top-left of the input before any cropping, lines dropped by
+//    Config.LineFilter included.
+func sourceAttrs(out io.Writer, src Source) string {
+	return string(appendSourceAttrs(nil, out, src))
+}
+
+// Appends to 'b' the attributes returned by sourceAttrs().
+func appendSourceAttrs(b []byte, out io.Writer, src Source) []byte {
+	d, ok := out.(*drawing)
+	if !ok || !d.sourceMap {
+		return b
+	}
+	attr := func(name string, v int) {
+		b = append(b, name...)
+		b = append(b, `="`...)
+		b = strconv.AppendInt(b, int64(v), 10)
+		b = append(b, '"')
+	}
+	row, col := d.source.Position(src.Start)
+	attr(" data-goat-row", row)
+	attr(" data-goat-col", col)
+	if src.isLine {
+		row, col = d.source.Position(src.Stop)
+		attr(" data-goat-end-row", row)
+		attr(" data-goat-end-col", col)
+	}
+	return b
+}
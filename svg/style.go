@@ -2,6 +2,9 @@ package svg
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/blampe/goat"
 )
 
 type (
@@ -22,6 +25,36 @@ type (
 		// selector list of a stylerule that contains GoAT-defined properties.
 		// XX  ? No need to retain -- move out to a local var in the parsing loop?
 		_idName string
+
+		// Set if the selector list names element 'svg' or pseudo-class ':root',
+		// the only rules that may carry 'roles'.
+		_rootRule bool
+
+		// Meaningful only in the binding keyed by 'runeRolesKey'.
+		roles RuneRoles
+
+		// Meaningful only in bindings keyed by glyphKey().
+		glyphProperties
+	}
+
+	// A rune to be drawn as an SVG <symbol> read from a file.
+	glyphProperties struct {
+		glyph    rune    // "goat-glyph"
+		glyphSrc string  // "goat-glyph-src"
+
+		// "goat-glyph-size"; zero is taken as 1.
+		glyphCols, glyphRows int
+	}
+
+	// Reassignments of runes from the ReservedSet of a dialect, applying to the
+	// whole diagram.
+	RuneRoles struct {
+		// Runes always to be drawn as text, e.g. "ov" to never draw circles or
+		// arrowheads from 'o' or 'v'.
+		TextRunes goat.RuneSet
+
+		// Runes to be drawn as graphics, as is the dialect's own '+' or '┼' joint.
+		JointRunes goat.RuneSet
 	}
 
 	// XX XX  Create a variant of this that identifies text ranges to be styled not by marks,
@@ -55,8 +88,27 @@ const (
 	//goat_anchor_substitutes	  = "goat-anchor-substitutes"
 
 	goat_anchor_href  = "goat-anchor-href"
+
+	goat_text_runes  = "goat-text-runes"
+	goat_joint_runes = "goat-joint-runes"
+
+	goat_glyph      = "goat-glyph"
+	goat_glyph_src  = "goat-glyph-src"
+	goat_glyph_size = "goat-glyph-size"
 )
 
+// X  The MarkBindingMap key of the single markBinding accumulating the RuneRoles
+//    declared by all 'svg' and ':root' rules.  Not a valid markpair.
+var runeRolesKey = markArr{-1, -1}
+
+// X  MarkBindingMap keys of markBindings with glyphProperties.  Not valid markpairs.
+func glyphKey(r rune) markArr {
+	return markArr{-2, r}
+}
+func isGlyphKey(ma markArr) bool {
+	return ma[0] == -2
+}
+
 func (mb markBinding) String() string {
 	return fmt.Sprintf(`
     markpair: %q` +
@@ -74,4 +126,20 @@ func (mb markBinding) String() string {
 	)
 }
 
+// Names the CSS rule(s) binding 'mb' in Diagnostics e.g. ".red.bold".
+func (mb *markBinding) classSelector() string {
+	if len(mb.ClassNames) == 0 {
+		return "(no class)"
+	}
+	return "." + strings.Join(mb.ClassNames, ".")
+}
+
+// The SVG element wrapping text between the marks.
+func (mb *markBinding) elementName() string {
+	if len(mb.HRef) > 0 {
+		return "a"
+	}
+	return "g"
+}
+
 var zeroMarkArr markArr = markArr{}
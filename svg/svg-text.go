@@ -1,20 +1,24 @@
 package svg
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"log"
+	"unicode/utf8"
 
 	"github.com/blampe/goat/internal"
 )
 
 type textDrawer struct {
 	config *Config
+	cc     *CanvasCommon
 
 	// Support nesting of SVG elements to name additional classes:
 	// Goal: Report input errors promptly.
 	wrapperStack []wrapper
+
+	// Glyphs whose <symbol> has been written.
+	definedGlyphs map[*glyph]bool
 }
 
 type wrapper struct {
@@ -25,40 +29,28 @@ type wrapper struct {
 func Writetext(out io.Writer, config *Config, ac AbstractCanvas) {
 	tD := textDrawer{
 		config: config,
+		cc: ac.GetCommon(),
+		definedGlyphs: make(map[*glyph]bool),
 	}
 	cc := ac.GetCommon()
 	for _, textObj := range cc.text() {
 		err := tD.Draw(out, textObj)
 		if err != nil {
-			// XX  Dump the filename as well.
-			log.Fatalf(`
-%s
-In Config %+v`,
-				err.Error(),
-				config,
-			)
+			d := AsDiagnostic(err, CodeUnmatchedMark)
+			cc.Diagnose(config, textObj.Start, d.Severity, d.Code, "%s", d.Message)
 		}
 	}
-	// diagnose bad remaining wrapperStack contents here
-	// XX share error state dumping code with Draw() below.
-	// XX XX  End users want helpful diagnostics.
-	//         XX  Passing an 'error' return up would require changing 3-4 levels of callers.
-	//         X   Alternative: set a field in Config 'exitStatus', to be checked by
-	//             interested callers.
-	if len(tD.wrapperStack) > 0 {
-		wrapper := tD.wrapperStack[0]
-		log.Fatalf(`
-len(tD.wrapperStack)==%d, should be empty.
-
-tD.wrapperStack[0] = {
-  markBinding: %s
-  text: %s
-}
+	// Report, then close, each begin mark left without its end mark, innermost last,
+	// so that the SVG remains well-formed.
+	for _, wrapper := range tD.wrapperStack {
+		cc.Diagnose(config, wrapper.text.Start, SeverityError, CodeUnclosedMark,
+			"begin mark '%c' of %s never closed by end mark '%c'",
+			wrapper.text.r, wrapper.markBinding.classSelector(), wrapper.markBinding.markpair[1])
+	}
+	for i := len(tD.wrapperStack) - 1; i >= 0; i-- {
+		internal.MustFPrintf(out, `  </%s>
 `,
-			len(tD.wrapperStack),
-			wrapper.markBinding.String(),
-			wrapper.text.String(),
-		)
+			tD.wrapperStack[i].markBinding.elementName())
 	}
 }
 
@@ -72,14 +64,28 @@ tD.wrapperStack[0] = {
 //               2. Makes SVG code easier to read.
 func (tD *textDrawer) Draw(out io.Writer, t text) error {
 	//character := string(t.r); _ = character   // for debug
+	g := tD.config.Geom()
 	textIndex := t.Start
-	p := textIndex.AsPixel()
+	p := textIndex.AsPixel(g)
 
 	// X  Whether t.r is actually a beginning, or rather and ending mark is not known at this point.
 	endMarkBinding, foundEndMark := tD.config.endMap[t.r]
 	beginMarkBinding, foundBeginMark := tD.config.beginMap[t.r]
 
 	handleBeginMark := func() {
+		// Nesting of <a> is forbidden by the spec; browsers disagree on the outcome.
+		if beginMarkBinding.elementName() == "a" {
+			for _, outer := range tD.wrapperStack {
+				if outer.markBinding.elementName() == "a" {
+					line, col := tD.cc.Position(outer.text.Start)
+					tD.cc.Diagnose(tD.config, t.Start, SeverityWarning, CodeNestedAnchor,
+						"link begun by '%c' of %s lies within link begun at line %d, column %d:" +
+							" nested <a> is invalid SVG",
+						t.r, beginMarkBinding.classSelector(), line, col)
+					break
+				}
+			}
+		}
 		tD.wrapperStack = append(tD.wrapperStack, 
 			wrapper{beginMarkBinding, t})
 
@@ -91,14 +97,12 @@ func (tD *textDrawer) Draw(out io.Writer, t text) error {
 			}
 			attrs += fmt.Sprintf("'")
 		}
-		elemString := "g"
-		if len(beginMarkBinding.HRef) > 0 {
-			// XX Nesting of <a> is forbidden by the spec -- how check input for violations?   
+		elemString := beginMarkBinding.elementName()
+		if elemString == "a" {
 			// X  Observed in browser: empty string "href=''" produces linking to the page itself.
 			//    Therefore, drop the href attribute entirely -- apparently functional equivalent
 			//    of a <g> element.
 			attrs += fmt.Sprintf(" href='%s'", beginMarkBinding.HRef)
-			elemString = "a"
 		}
 		internal.MustFPrintf(out, `  <%s%s>
 `,
@@ -106,7 +110,7 @@ func (tD *textDrawer) Draw(out io.Writer, t text) error {
 		//subst := beginMarkBinding.subst[0]
 		//
 		//if subst != 0 {
-		//	finalDraw(out, p, subst)
+		//	finalDraw(out, g, p, subst)
 		//}
 	}
 
@@ -117,12 +121,9 @@ func (tD *textDrawer) Draw(out io.Writer, t text) error {
 				handleBeginMark()
 				return nil
 			}
-			return errors.New(fmt.Sprintf(
-				// toggling case?
-				"\n\tEnd mark %s for\n" +
-					"\t\t markpairclass %+v found at\n" +
-					"\t\tIndex %+v, but no matching start mark on stack",
-				string(t.r), endMarkBinding, t.Start))
+			// toggling case?
+			return fmt.Errorf("end mark '%c' of %s, but no begin mark '%c' open",
+				t.r, endMarkBinding.classSelector(), endMarkBinding.markpair[0])
 		}
 
 		// Verify that t.r is the specific end mark expected in accordance with
@@ -135,56 +136,75 @@ func (tD *textDrawer) Draw(out io.Writer, t text) error {
 				handleBeginMark()
 				return nil
 			}
-			tosMarkBindingEndMark := tosMarkBinding.markpair[1]
-			return errors.New(fmt.Sprintf(
-				"\tAt line %d, column %d scanned unexpected end mark" +
-					"\n\t%s (0x%x) for markpairclass" +
-					"\n\t\t%s" +
-					"\n\texpected end mark" +
-					"\n\t%s (0x%x) for markpairclass" +
-					"\n\t\t%s",
-				textIndex.Y, textIndex.X,
-				string(t.r), t.r,
-				formatMarkBinding(endMarkBinding),
-				string(tosMarkBindingEndMark), tosMarkBindingEndMark,
-				formatMarkBinding(tosMarkBinding)))
+			line, col := tD.cc.Position(tosWrapper.text.Start)
+			return fmt.Errorf("unexpected end mark '%c' of %s: expected end mark '%c' of %s," +
+				" begun at line %d, column %d",
+				t.r, endMarkBinding.classSelector(),
+				tosMarkBinding.markpair[1], tosMarkBinding.classSelector(),
+				line, col)
 		}
 		//// Special case: do not show a styled SPACE (possibly underlined)
 		//// as the replacement for an end Mark character.
 		//subst := endMarkBinding.subst[1]
 		//if subst != 0 {
-		//	finalDraw(out, p, subst)
+		//	finalDraw(out, g, p, subst)
 		//}
 
-		elemString := "g"
-		if len(endMarkBinding.HRef) > 0 {
-			elemString = "a"
-		}
 		internal.MustFPrintf(out, `  </%s>
 `,
-			elemString)
+			endMarkBinding.elementName())
 		// pop the stack
 		tD.wrapperStack = tD.wrapperStack[:len(tD.wrapperStack)-1]
 		return nil
 	}
 	if foundBeginMark {
 		handleBeginMark()
+	} else if gl, found := tD.config.glyphs[t.r]; found {
+		gl.draw(out, g, textIndex, tD.definedGlyphs)
 	} else {
-		finalDraw(out, p, t.r)
+		finalDraw(out, g, p, t, SourceCell(textIndex))
 	}
 	return nil
 }
 
 
-func finalDraw(out io.Writer, p Pixel, r rune) {
+// Append 'c' to 'b', escaped for XML character data -- every byte, since a grapheme
+// cluster may begin with '&' or '<'.
+func appendEscapedText(b, c []byte) []byte {
+	for _, ch := range c {
+		switch ch {
+		case '&':
+			b = append(b, "&amp;"...)
+		case '<':
+			b = append(b, "&lt;"...)
+		case '>':
+			b = append(b, "&gt;"...)
+		default:
+			b = append(b, ch)
+		}
+	}
+	return b
+}
+
+// Draw 't' centered on 'p', or if a wide character, on the pair of cells from 'p'.
+func finalDraw(out io.Writer, g *Geometry, p Pixel, t text, src Source) {
+	r := t.r
 	if r == 0 {
 		log.Panicf("NULL rune received")
 	}
-	c := string(r)
+	// X  Formatted into 'runeBuf', lest string(r) allocate for every rune drawn.
+	var runeBuf [utf8.UTFMax]byte
+	c := utf8.AppendRune(runeBuf[:0], r)
+	if len(t.cluster) > 0 {
+		c = []byte(t.cluster)
+	}
+	if t.cells > 1 {
+		p.X += g.CellWidth * float64(t.cells-1) / 2
+	}
 	if len(c) <= 0 {
 		log.Panicf("rune %#v yielded empty string!", r)
 	}
-	if c == " " {
+	if string(c) == " " {
 		// <text> elements containing only a SPC character can always be safely dropped, yes?
 		// XX  Could this case be eliminated earlier in processing?
 		return
@@ -193,7 +213,7 @@ func finalDraw(out io.Writer, p Pixel, r rune) {
 
 	// Markdeep special-cases these characters and treats them like a
 	// checkerboard.
-	switch c {
+	switch string(c) {
 	case "▉":
 		opacity = -9999
 	case "▓":
@@ -209,35 +229,41 @@ func finalDraw(out io.Writer, p Pixel, r rune) {
 		if opacity > 0 {
 			fill = fmt.Sprintf("rgb(%d,%d,%d)", opacity, opacity, opacity)
 		}
+		W, H := g.CellWidth, g.CellHeight
 		internal.MustFPrintf(out,
-			`<rect x="%d" y="%d" width="%d" height="%d" fill="%s"></rect>
+			`<rect x="%s" y="%s" width="%s" height="%s" fill="%s"%s></rect>
 `,
-			p.X-W/2, p.Y-H/2,
-			W, H,
-			fill)
+			coord(p.X-W/2), coord(p.Y-H/2),
+			coord(W), coord(H),
+			fill,
+			sourceAttrs(out, src))
+		extend(out, func(e *Extents) {
+			e.IncludeBox(Pixel{W/2, H/2}, p)
+		})
 		return
 	}
 
-	// Escape for XML
-	switch c {
-	case "&":
-		c = "&amp;"
-	case ">":
-		c = "&gt;"
-	case "<":
-		c = "&lt;"
-	}
-
 	// usual case
 
-	// Text elements <text> get an inline Y-offset of +4 – visually necessary for Y-alignment
+	// Text elements <text> get an inline Y-offset, by default +4 – visually necessary for Y-alignment
 	// with Dots to left or right.
-	// The value +4 is in theory font-specific, but for common fonts it corresponds to the offset
+	// The value is font-specific, but for common fonts +4 corresponds to the offset
 	// from the center of the 8x16 cell and the "baseline" typical of Roman fonts, which
 	// aligns with for example the bottom of the "bowl" of a lower-case 'g'.
 	//     https://svgwg.org/svg2-draft/text.html#FontsGlyphs
-	centerToBaseline := 4
-	internal.MustFPrintf(out, `    <text x="%d" y="%d">%s</text>
-`,
-		p.X, p.Y+centerToBaseline, c)
+	centerToBaseline := g.BaselineOffset
+	b := elementBuffer(out)
+	b = append(b, `    <text x="`...)
+	b = appendCoord(b, p.X)
+	b = append(b, `" y="`...)
+	b = appendCoord(b, p.Y+centerToBaseline)
+	b = append(b, '"')
+	b = appendSourceAttrs(b, out, src)
+	b = append(b, '>')
+	b = appendEscapedText(b, c)
+	b = append(b, "</text>\n"...)
+	writeElement(out, b)
+	extend(out, func(e *Extents) {
+		e.IncludeBox(Pixel{g.CellWidth*float64(t.cells)/2, g.CellHeight/2}, p)
+	})
 }
@@ -3,69 +3,42 @@ package svg
 import (
 	"fmt"
 	"io"
+	"math"
+	"strconv"
 
 	"github.com/blampe/goat/internal"
 )
 
-func (cc *CanvasCommon) OpenSvgElement() string {
-	return fmt.Sprintf(
-`<svg xmlns="http://www.w3.org/2000/svg" version="1.1"
-    width="%d" height="%d"
-    viewBox="0 0 %d %d">
-`,
-		cc.widthScreen(), cc.heightScreen(),
-		cc.widthScreen(), cc.heightScreen(),
-	)
-}
-
-func (c *CanvasCommon) heightScreen() int {
-	// " + 8 + 2", because any less results in clipping of any edge at the bottom of the drawing
-	//    XX  Necessary because fragilely tuned to 'edgeToCenterY', below.
-	return c.Height*CellHeight + 8 + 2
-}
-
-func (c *CanvasCommon) widthScreen() int {
-	// XX  "c.Width + 1", fragilely tuned to 'edgeToCenterX', below.
-	return (c.Width + 1) * CellWidth
-}
-
 func CloseSvgElement() string {
 	return `</svg>
 `
 }
 
-// We desire that pixel coordinate {0,0} should lie at the *center* of the 8x16
-// "cell" at top-left corner of the enclosing SVG element, and that a
-// visually-pleasing margin separate that cell from the visible top-left
-// corner; the 'translate()' below accomplishes that.
-//
-// X The former 16-pixel Y-axis translation of the transform was more than necessary – there
-// is an always-blank band at the top of SVG image.
-func OpenGElement() string {
-	edgeToCenterX, edgeToCenterY :=
-		CellWidth, CellHeight*3/4
-
-	return fmt.Sprintf(`
-<g transform='translate(%d,%d)'>
-`,
-		edgeToCenterX, edgeToCenterY)
-}
-
 func CloseGElement() string {
 	return `</g>
 `
 }
 
-func WritePolyline(out io.Writer, start, stop Pixel) {
-	internal.MustFPrintf(out, `    <polyline class="path" points="%d,%d %d,%d"/>
-`,
-		start.X, start.Y,
-		stop.X, stop.Y,
-	)
+func WritePolyline(out io.Writer, start, stop Pixel, src Source) {
+	extend(out, func(e *Extents) {
+		e.IncludeStroked(start, stop)
+	})
+	if record(out, stroke{from: start, to: stop, src: src}) {
+		return
+	}
+	b := elementBuffer(out)
+	b = append(b, `    <polyline class="path" points="`...)
+	b = start.appendTo(b)
+	b = append(b, ' ')
+	b = stop.appendTo(b)
+	b = append(b, '"')
+	b = appendSourceAttrs(b, out, src)
+	b = append(b, "/>\n"...)
+	writeElement(out, b)
 }
 
 // Draw a solid triangle as an SVG polygon element.
-func (t Triangle) Draw(out io.Writer) {
+func (t Triangle) Draw(out io.Writer, g *Geometry) {
 	// https://www.w3.org/TR/SVG/shapes.html#PolygonElement
 
 	/*
@@ -79,20 +52,22 @@ func (t Triangle) Draw(out io.Writer) {
 			y
 	*/
 
-	x, y := float32(t.Start.AsPixel().X), float32(t.Start.AsPixel().Y)
+	x, y := float32(t.Start.AsPixel(g).X), float32(t.Start.AsPixel(g).Y)
+	W, H := float32(g.CellWidth), float32(g.CellHeight)
+	size := float32(g.ArrowSize)
 	r := 0.0
 
 	// Coordinate values below are effective verbatim only for O_E, an isosceles
 	// triangle "pointing" rightward; rotation to the desired final direction
 	// is a post-process.
-	// If regarded as an arrow-point, it will be 12px long and
-	// 0.35*16*2=11.2px in width.
+	// If regarded as an arrow-point, it will by default be 1.5*W=12px long and
+	// 0.35*H*2=11.2px in width.  Scaling by 'size' holds the tip fixed.
 	x0 := x + W
 	y0 := y
-	x1 := x - W/2
-	y1 := y - 0.35*CellHeight
-	x2 := x - W/2
-	y2 := y + 0.35*CellHeight
+	x1 := x0 - 1.5*W*size
+	y1 := y - 0.35*H*size
+	x2 := x0 - 1.5*W*size
+	y2 := y + 0.35*H*size
 
 	// 't.NeedsNudging' in all cases below means "put the tip of the arrowhead
 	// on the boundary of the next cell".
@@ -112,9 +87,9 @@ func (t Triangle) Draw(out io.Writer) {
 		x1 += W/2
 		x2 += W/2
 		if t.NeedsNudging {
-			x0 += 6
-			x1 += 6
-			x2 += 6
+			x0 += 3*W/4
+			x1 += 3*W/4
+			x2 += 3*W/4
 		}
 	case O_NW:
 		r = 240
@@ -122,9 +97,9 @@ func (t Triangle) Draw(out io.Writer) {
 		x1 += W/2
 		x2 += W/2
 		if t.NeedsNudging {
-			x0 += 6
-			x1 += 6
-			x2 += 6
+			x0 += 3*W/4
+			x1 += 3*W/4
+			x2 += 3*W/4
 		}
 	case O_W:
 		r = 180
@@ -153,9 +128,9 @@ func (t Triangle) Draw(out io.Writer) {
 		x1 += W/2
 		x2 += W/2
 		if t.NeedsNudging {
-			x0 += 6
-			x1 += 6
-			x2 += 6
+			x0 += 3*W/4
+			x1 += 3*W/4
+			x2 += 3*W/4
 		}
 	case O_SE:
 		r = 60
@@ -163,14 +138,13 @@ func (t Triangle) Draw(out io.Writer) {
 		x1 += W/2
 		x2 += W/2
 		if t.NeedsNudging {
-			x0 += 6
-			x1 += 6
-			x2 += 6
+			x0 += 3*W/4
+			x1 += 3*W/4
+			x2 += 3*W/4
 		}
 	}
 
-	// <polygon> inherits both 'fill' and 'stroke' attributes from parents.
-	internal.MustFPrintf(out, PolygonPrintFmt,
+	WriteArrowhead(out, SourceCell(t.Start),
 		x0, y0,
 		x1, y1,
 		x2, y2,
@@ -178,37 +152,127 @@ func (t Triangle) Draw(out io.Writer) {
 		x, y)
 }
 
+// Deprecated: arrowheads are written by WriteArrowhead(), which alone knows of the
+// -compact and -source-map forms.
 const PolygonPrintFmt = `    <polygon points="%g,%g %g,%g %g,%g" transform="rotate(%g, %g, %g)" class="arrowhead"></polygon>
 `
 
+// Appends the one form of an arrowhead: a <polygon> of vertices 'points', with any
+// 'transform' and the source attributes of 'src' for 'out', each preceded by a space.
+// X  <polygon> inherits both 'fill' and 'stroke' attributes from parents.
+func appendArrowhead(b, points, transform []byte, out io.Writer, src Source) []byte {
+	b = append(b, `<polygon points="`...)
+	b = append(b, points...)
+	b = append(b, '"')
+	b = append(b, transform...)
+	b = append(b, ` class="arrowhead"`...)
+	b = appendSourceAttrs(b, out, src)
+	return append(b, "></polygon>"...)
+}
+
+// Write a triangle of vertices {xN,yN}, rotated by 'r' degrees about {x,y}.
+func WriteArrowhead(out io.Writer, src Source,
+	x0, y0, x1, y1, x2, y2 float32,
+	r float64,
+	x, y float32) {
+
+	defer extend(out, func(e *Extents) {
+		center := Pixel{float64(x), float64(y)}
+		for _, p := range []Pixel{
+			{float64(x0), float64(y0)},
+			{float64(x1), float64(y1)},
+			{float64(x2), float64(y2)},
+		} {
+			e.IncludeStroked(rotate(p, r, center))
+		}
+	})
+	// Formatted as would be "%g".
+	g := func(b []byte, v float32) []byte {
+		return strconv.AppendFloat(b, float64(v), 'g', -1, 32)
+	}
+	var transformBuf [64]byte
+	transform := append(transformBuf[:0], ` transform="rotate(`...)
+	transform = strconv.AppendFloat(transform, r, 'g', -1, 64)
+	transform = append(transform, ", "...)
+	transform = g(transform, x)
+	transform = append(transform, ", "...)
+	transform = g(transform, y)
+	transform = append(transform, `)"`...)
+
+	if d := compacting(out); d != nil {
+		// The same shape recurs, differing only in position and rotation.
+		// X  Offsets rounded as by compactBody(), so that float32 noise does not
+		//    distinguish otherwise identical shapes.
+		rel := func(a, b float32) string {
+			return coord(roundTo(float64(a-b), compactDecimals))
+		}
+		points := fmt.Sprintf("%s,%s %s,%s %s,%s",
+			rel(x0, x), rel(y0, y),
+			rel(x1, x), rel(y1, y),
+			rel(x2, x), rel(y2, y))
+		id := d.symbols.id(string(appendArrowhead(nil, []byte(points), nil, nil, src)))
+		internal.MustFPrintf(out, `<use %s x="%g" y="%g"%s%s/>
+`,
+			useHref(out, id), x, y, transform, sourceAttrs(out, src))
+		return
+	}
+
+	var pointsBuf [96]byte
+	points := pointsBuf[:0]
+	for i, v := range [6]float32{x0, y0, x1, y1, x2, y2} {
+		if i > 0 {
+			points = append(points, " ,"[i%2])
+		}
+		points = g(points, v)
+	}
+	b := append(elementBuffer(out), "    "...)
+	b = appendArrowhead(b, points, transform, out, src)
+	writeElement(out, append(b, '\n'))
+}
+
 // Draw a solid circle as an SVG circle element.
-func (ci *Circle) Draw(out io.Writer, circleRadius int) {
+func (ci *Circle) Draw(out io.Writer, g *Geometry, circleRadius float64) {
 	var class string
 	if ci.Bold {    // bad name?
 		class = "filled"
 	} else {
 		class = "hollow"
 	}
-	pixel := ci.Start.AsPixel()
-	internal.MustFPrintf(out,
-		`    <circle cx="%d" cy="%d" r="%d" class="%s"></circle>
-`,
-		pixel.X,
-		pixel.Y,
-		circleRadius,
-		class,
-	)
-}
+	pixel := ci.Start.AsPixel(g)
+	defer extend(out, func(e *Extents) {
+		e.IncludeStroked(
+			Pixel{pixel.X - circleRadius, pixel.Y - circleRadius},
+			Pixel{pixel.X + circleRadius, pixel.Y + circleRadius})
+	})
 
-func formatMarkBinding(s *markBinding) string {
-	return fmt.Sprintf("%+v", s)
+	if d := compacting(out); d != nil {
+		id := d.symbols.id(fmt.Sprintf(`<circle r="%s" class="%s"></circle>`,
+			coord(circleRadius), class))
+		internal.MustFPrintf(out, `<use %s x="%s" y="%s"%s/>
+`,
+			useHref(out, id), coord(pixel.X), coord(pixel.Y), sourceAttrs(out, SourceCell(ci.Start)))
+		return
+	}
+	b := elementBuffer(out)
+	b = append(b, `    <circle cx="`...)
+	b = appendCoord(b, pixel.X)
+	b = append(b, `" cy="`...)
+	b = appendCoord(b, pixel.Y)
+	b = append(b, `" r="`...)
+	b = appendCoord(b, circleRadius)
+	b = append(b, `" class="`...)
+	b = append(b, class...)
+	b = append(b, '"')
+	b = appendSourceAttrs(b, out, SourceCell(ci.Start))
+	b = append(b, "></circle>\n"...)
+	writeElement(out, b)
 }
 
 // Draw a rounded corner as an SVG "elliptical arc" element, here merely a circular arc
 // across one of the four axis-aligned quadrants.
 //
 //   ASCII:
-//     Span a _pair_ of left-right adjacent 8x16 cells, one of which contains an ASCII space, that
+//     Span a _pair_ of left-right adjacent cells, one of which contains an ASCII space, that
 //     being the one at the corner of the connected line segments.
 //            .-.
 //           |   |    a circle
@@ -218,70 +282,91 @@ func formatMarkBinding(s *markBinding) string {
 //             ╭╮
 //             ╰╯     output will be a true oval -- but no circle is possible
 //
-func (rc *RoundedCorner) DrawCentered(out io.Writer, centerPixel Pixel, radius int) {
+// Arg 'radius' carries distinct X and Y radii, which are equal only if the corner is circular.
+func (rc *RoundedCorner) DrawCentered(out io.Writer, centerPixel Pixel, radius Pixel) {
 	// https://www.w3.org/TR/SVG/paths.html#PathDataEllipticalArcCommands
 
 	x, y := centerPixel.X, centerPixel.Y
-	var startX, startY, sweepFlag, endX, endY int
+	var start, end Pixel
+	var sweepFlag int
 
 	switch rc.Orientation {
 	case O_NW:
-		startX = x
-		startY = y - radius
+		start = Pixel{x, y - radius.Y}
 		sweepFlag = 0  // counter-clockwise
-		endX = x - radius
-		endY = y
+		end = Pixel{x - radius.X, y}
 	case O_SW:
-		startX = x - radius
-		startY = y
+		start = Pixel{x - radius.X, y}
 		sweepFlag = 0  // counter-clockwise
-		endX = x
-		endY = y + radius
+		end = Pixel{x, y + radius.Y}
 	case O_NE:
-		startX = x
-		startY = y - radius
+		start = Pixel{x, y - radius.Y}
 		sweepFlag = 1  // clockwise
-		endX = x + radius
-		endY = y
+		end = Pixel{x + radius.X, y}
 	case O_SE:
-		startX = x + radius
-		startY = y
+		start = Pixel{x + radius.X, y}
 		sweepFlag = 1  // clockwise
-		endX = x
-		endY = y + radius
+		end = Pixel{x, y + radius.Y}
+	}
+
+	// X  A quarter-circle aligned to the axes lies within the box spanned by its ends.
+	extend(out, func(e *Extents) {
+		e.IncludeStroked(start, end)
+	})
+	src := SourceCell(rc.Start)
+	if record(out, stroke{from: start, to: end, radius: radius, sweepFlag: sweepFlag, src: src}) {
+		return
 	}
 
 	// X  Assumes inherited "fill: none"
 	internal.MustFPrintf(out,
-		`    <path class="path" d="M %d,%d A %d,%d %d %d,%d %d,%d"></path>
+		`    <path class="path" d="M %s A %s %d %d,%d %s"%s></path>
 `,
-		startX,
-		startY,
-		radius, // x-radius
-		radius, // y-radius
+		start,
+		radius, // x-radius, y-radius
 		0, // x-axis-rotation
 		0, // large-arc-flag
 		sweepFlag,
-		endX,  // absolute end position, as implied by SVG command 'A'
-		endY,  // absolute end position, as implied by SVG command 'A'
+		end,  // absolute end position, as implied by SVG command 'A'
+		sourceAttrs(out, src),
 	)
 }
 
 // Draw a bridge as an SVG elliptical arc element.
-func (b Bridge) Draw(out io.Writer) {
-	x, y := b.Start.AsPixelXY()
+func (b Bridge) Draw(out io.Writer, g *Geometry) {
+	x, y := b.Start.AsPixelXY(g)
+	H := g.CellHeight
 	sweepFlag := 1
 
 	if b.Orientation == O_W {
 		sweepFlag = 0
 	}
 
+	// Radius 9/16 of the cell height: slightly more than half, so that the arc bulges.
+	r := H*9/16
+	start, end := Pixel{x, y-H/2}, Pixel{x, y+H/2}
+
+	extend(out, func(e *Extents) {
+		// The arc bulges sideways from its chord by its sagitta: rightward if clockwise.
+		sagitta := r - math.Sqrt(r*r - H*H/4)
+		if sweepFlag == 0 {
+			sagitta = -sagitta
+		}
+		e.IncludeStroked(start, end, Pixel{x + sagitta, y})
+	})
+	src := SourceCell(b.Start)
+	if record(out, stroke{from: start, to: end, radius: Pixel{r, r}, sweepFlag: sweepFlag, src: src}) {
+		return
+	}
+
 	// X  Assumes inherited "fill: none"
 	internal.MustFPrintf(out,
-		`    <path class="path" d="M %d,%d A 9,9 0 0,%d %d,%d"></path>
+		`    <path class="path" d="M %s A %s,%s 0 0,%d %s"%s></path>
 `,
-		x, y-H/2,
+		start,
+		coord(r), coord(r),
 		sweepFlag,
-		x, y+H/2,
+		end,
+		sourceAttrs(out, src),
 	)
 }
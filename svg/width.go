@@ -0,0 +1,53 @@
+package svg
+
+import (
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// Content of the second of the two cells covered by a wide character, classified as
+// text and never drawn.
+const wideTail rune = -1
+
+// Width in cells of 'r', the first rune of a grapheme cluster, as displayed by a
+// terminal: 2 for East Asian Wide and Fullwidth characters, otherwise 1.
+//
+//	https://www.unicode.org/reports/tr11/
+func runeWidth(r rune) int {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+const (
+	zeroWidthJoiner    = '\u200D'
+	zeroWidthNonJoiner = '\u200C'
+)
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// Whether 'r' continues the grapheme cluster of which 'prev' is the last rune so far,
+// rather than beginning a cluster of its own.
+//
+// X  Approximates the rules of UAX #29 for what may appear in a diagram: combining
+//    marks, joiners, and the runes following ZERO WIDTH JOINER in emoji sequences,
+//    emoji modifiers and tags.  Pairs of regional indicators are handled by the caller.
+//        https://www.unicode.org/reports/tr29/
+func extendsCluster(prev, r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == zeroWidthJoiner || r == zeroWidthNonJoiner:
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF:   // EMOJI MODIFIER FITZPATRICK
+		return true
+	case r >= 0xE0020 && r <= 0xE007F:   // TAG
+		return true
+	}
+	return prev == zeroWidthJoiner
+}
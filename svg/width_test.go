@@ -0,0 +1,59 @@
+package svg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWideAndClusters(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(runeWidth('a'), qt.Equals, 1)
+	c.Assert(runeWidth('─'), qt.Equals, 1)
+	c.Assert(runeWidth('日'), qt.Equals, 2)
+	c.Assert(runeWidth('Ａ'), qt.Equals, 2)
+	c.Assert(runeWidth('😀'), qt.Equals, 2)
+
+	// "e" with COMBINING ACUTE ACCENT, a CJK pair, and a flag of two regional indicators.
+	config := Config{}
+	ac := &allText{NewCanvasCommon(&config, strings.NewReader("é|日本|🇯🇵|"))}
+	c.Assert(ac.Width, qt.Equals, 9)
+	for x, want := range []rune{'e', '|', '日', wideTail, '本', wideTail, '|', 0x1F1EF, '|'} {
+		r, _ := ac.DataRuneAt(XyIndex{x, 0})
+		c.Assert(r, qt.Equals, want, qt.Commentf("x=%d", x))
+	}
+	c.Assert(CanvasString(ac), qt.Equals, "e\u0301|日本|🇯🇵|\n")
+	MoveToText(ac)
+	texts := ac.text()
+	c.Assert(texts, qt.HasLen, 7)
+	c.Assert(texts[0].cluster, qt.Equals, "é")
+	c.Assert(texts[2].cells, qt.Equals, 2)
+	c.Assert(texts[5].cluster, qt.Equals, "🇯🇵")
+
+	var out bytes.Buffer
+	Writetext(&out, &config, ac)
+	c.Assert(out.String(), qt.Contains, `<text x="0" y="4">e`+"́"+`</text>`)
+	// Centered between its two cells, the third and fourth.
+	c.Assert(out.String(), qt.Contains, `<text x="20" y="4">日</text>`)
+
+	// A crop through the middle of the clusters keeps those within it.
+	ac.crop(1, 0, 9, 1)
+	c.Assert(ac.text()[4].cluster, qt.Equals, "🇯🇵")
+}
+
+func TestClusterEscaped(t *testing.T) {
+	c := qt.New(t)
+
+	// "&" with COMBINING ACUTE ACCENT, and "<" with COMBINING LONG SOLIDUS OVERLAY.
+	config := Config{}
+	ac := &allText{NewCanvasCommon(&config, strings.NewReader("&\u0301<\u0338>"))}
+	MoveToText(ac)
+	var out bytes.Buffer
+	Writetext(&out, &config, ac)
+	c.Assert(out.String(), qt.Contains, ">&amp;\u0301</text>")
+	c.Assert(out.String(), qt.Contains, ">&lt;\u0338</text>")
+	c.Assert(out.String(), qt.Contains, ">&gt;</text>")
+}
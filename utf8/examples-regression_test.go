@@ -18,5 +18,9 @@ func TestMain(m *testing.M) {
 }
 
 func TestExamples(t *testing.T) {
-	testlib.Regression(t, utf8.ReservedSet, utf8.NewCanvas)
+	testlib.RegressionDialect(t, "utf8")
+}
+
+func BenchmarkExamples(b *testing.B) {
+	testlib.BenchmarkExamples(b, utf8.NewCanvas)
 }
@@ -34,15 +34,18 @@ var roundedCornerRunes = goat.MakeRuneSet(
 	'╯',  //  svg.O_SE:  O_E stop   O_S stop
 )
 
+// In units of cornerRadius.
 var roundedCornerCenters = map[svg.Orientation]svg.Pixel{
-	svg.O_NW: { X: cornerRadius, Y: cornerRadius},   // '╭'
-	svg.O_SW: { X: cornerRadius, Y:-cornerRadius},   // '╰'
-	svg.O_NE: { X:-cornerRadius, Y: cornerRadius},   // '╮'
-	svg.O_SE: { X:-cornerRadius, Y:-cornerRadius},   // '╯'
+	svg.O_NW: { X: 1, Y: 1},   // '╭'
+	svg.O_SW: { X: 1, Y:-1},   // '╰'
+	svg.O_NE: { X:-1, Y: 1},   // '╮'
+	svg.O_SE: { X:-1, Y:-1},   // '╯'
 }
-func (c *Canvas) CenterPixel(rc svg.RoundedCorner) svg.Pixel {
-	cellCenter := rc.Start.AsPixel()
-	return cellCenter.Sum(roundedCornerCenters[rc.Orientation])
+func (c *Canvas) CenterPixel(g *svg.Geometry, rc svg.RoundedCorner) svg.Pixel {
+	cellCenter := rc.Start.AsPixel(g)
+	unit := roundedCornerCenters[rc.Orientation]
+	radius := cornerRadius(g)
+	return cellCenter.Sum(svg.Pixel{X: unit.X*radius, Y: unit.Y*radius})
 }
 
 var boxJointRunes = goat.UnionSets(
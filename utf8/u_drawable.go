@@ -7,13 +7,13 @@ import (
 	"github.com/blampe/goat/svg"
 )
 
-const (
-	W = svg.CellWidth   // XX DRY with hard-coded constants in a_line.go et al.   
-	H = svg.CellHeight
-
-	CIRCLERADIUS = W/2
-	cornerRadius = W/2
-)
+// Both default to half the cell width, so that each lies within its own cell.
+func circleRadius(g *svg.Geometry) float64 {
+	return g.CircleRadiusOr(0.5)
+}
+func cornerRadius(g *svg.Geometry) float64 {
+	return g.CellWidth/2
+}
 
 // WriteSVGBody writes the entire content of a Canvas out to a stream in SVG format.
 // XX Produces a complete <g ...>...</g> expression -- rename accordingly?
@@ -22,23 +22,24 @@ func (c *Canvas) WriteSVGBody(dst io.Writer, config *svg.Config) {
 	wb := func(fmt string, s ...interface{}) {
 		internal.MustFPrintf(dst, fmt, s...)
 	}
+	g := config.Geom()
 
 	wb("  <g id='%s'>\n", "lines-vertical")
 	for _, lv := range c.getlines(svg.UpDownMinor, svg.O_S) {
-		c.DrawLine(lv, dst)
+		c.DrawLine(lv, g, dst)
 	}
 	wb("  </g>\n")
 
 	wb("  <g id='%s'>\n", "lines-horizontal")
 	for _, lh := range c.getlines(svg.LeftRightMinor, svg.O_E) {
-		c.DrawLine(lh, dst)
+		c.DrawLine(lh, g, dst)
 	}
 	wb("  </g>\n")
 
 	// XX unify with '/ascii'
 	wb("  <g id='%s'>\n", "triangles")
 	for _, t := range c.triangles() {
-		t.Draw(dst)
+		t.Draw(dst, g)
 	}
 	wb("  </g>\n")
 
@@ -48,14 +49,15 @@ func (c *Canvas) WriteSVGBody(dst io.Writer, config *svg.Config) {
 	//      An svg.RoundedCorner struct contains two fields, Start and Orientation
 	wb("  <g id='%s'>\n", "roundedCorners")
 	for _, rc := range c.roundedCorners() {
-		rc.DrawCentered(dst, c.CenterPixel(rc), cornerRadius)
+		radius := cornerRadius(g)
+		rc.DrawCentered(dst, c.CenterPixel(g, rc), svg.Pixel{X: radius, Y: radius})
 	}
 	wb("  </g>\n")
 
 	// XX unify with '/ascii'
 	wb("  <g id='%s'>\n", "circles")
 	for _, ci := range c.circles() {
-		ci.Draw(dst, CIRCLERADIUS)
+		ci.Draw(dst, g, circleRadius(g))
 	}
 	wb("  </g>\n")
 
@@ -114,26 +114,27 @@ func (c *Canvas) getlines(
 //   3. Isolated joints e.g. '┬' or '┼': Decompose into horizontal and vertical,
 //      each independent of the other.
 
-func (c *Canvas) DrawLine(l line, out io.Writer) {
-	startPix := c.startingPixel(l)
-	stopPix := c.stoppingPixel(l)
+func (c *Canvas) DrawLine(l line, g *svg.Geometry, out io.Writer) {
+	startPix := c.startingPixel(l, g)
+	stopPix := c.stoppingPixel(l, g)
 
 	if startPix.X == stopPix.X && startPix.Y == stopPix.Y {
 		return
 	}
 
-	svg.WritePolyline(out, startPix, stopPix)
+	svg.WritePolyline(out, startPix, stopPix, svg.SourceLine(l.Start, l.Stop))
 }
 
-func (c *Canvas) startingPixel(l line) svg.Pixel {
+func (c *Canvas) startingPixel(l line, g *svg.Geometry) svg.Pixel {
+	W, H := g.CellWidth, g.CellHeight
 	// initial values of these are at centers of cells -- possibly adjusted later
-	startPix := l.Start.AsPixel()
+	startPix := l.Start.AsPixel(g)
 	startRune := c.RuneAt(l.Start)
 
 	switch l.Orientation {
 	case svg.O_E:
 		if startRune == '╭' || startRune == '╰' {
-			startPix.X += cornerRadius
+			startPix.X += cornerRadius(g)
 		} else if connects[reverse(l.Orientation)].Contains(startRune) {
 			westRune := c.RuneAt(l.Start.West())
 			startTriangleBase := leftArrowheadRunes.Contains(westRune)
@@ -145,7 +146,7 @@ func (c *Canvas) startingPixel(l line) svg.Pixel {
 		}
 	case svg.O_S:
 		if startRune == '╭' || startRune == '╮' {
-			startPix.Y += cornerRadius
+			startPix.Y += cornerRadius(g)
 		} else if connects[reverse(l.Orientation)].Contains(startRune) {
 			// If either end abuts a circle, extend drawing to the edge of the circle,
 			// rather extending as usual to center of the cell.
@@ -163,15 +164,16 @@ func (c *Canvas) startingPixel(l line) svg.Pixel {
 	return startPix
 }
 
-func (c *Canvas) stoppingPixel(l line) svg.Pixel {
+func (c *Canvas) stoppingPixel(l line, g *svg.Geometry) svg.Pixel {
+	W, H := g.CellWidth, g.CellHeight
 	// initial values of these are at centers of cells -- possibly adjusted later
-	stopPix := l.Stop.AsPixel()
+	stopPix := l.Stop.AsPixel(g)
 	stopRune := c.RuneAt(l.Stop)
 
 	switch l.Orientation {
 	case svg.O_E:
 		if stopRune == '╮' || stopRune == '╯' {
-			stopPix.X -= cornerRadius
+			stopPix.X -= cornerRadius(g)
 		} else if connects[l.Orientation].Contains(stopRune) {
 			eastRune := c.RuneAt(l.Stop.East())
 			stopTriangleBase := rightArrowheadRunes.Contains(eastRune)
@@ -184,7 +186,7 @@ func (c *Canvas) stoppingPixel(l line) svg.Pixel {
 		}
 	case svg.O_S:
 		if stopRune == '╯' || stopRune == '╰' {
-			stopPix.Y -= cornerRadius
+			stopPix.Y -= cornerRadius(g)
 		} else if connects[l.Orientation].Contains(stopRune) {
 			// If either end abuts a circle, extend drawing to the edge of the circle,
 			// rather extending as usual to center of the cell.
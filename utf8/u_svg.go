@@ -3,7 +3,6 @@ package utf8
 import (
 	"io"
 
-	"github.com/blampe/goat/internal"
 	"github.com/blampe/goat/svg"
 )
 
@@ -13,15 +12,15 @@ type smallTriangle struct {
 }
 
 // Draw a solid triangle as an SVG polygon element.
-func (t smallTriangle) Draw(out io.Writer) {
-	x, y := float32(t.Start.AsPixel().X), float32(t.Start.AsPixel().Y)
+func (t smallTriangle) Draw(out io.Writer, g *svg.Geometry) {
+	x, y := float32(t.Start.AsPixel(g).X), float32(t.Start.AsPixel(g).Y)
+	W := float32(g.CellWidth)
 	r := 0.0
 
 	// Coordinate values below are effective verbatim only for O_E, an isosceles
 	// triangle "pointing" rightward; rotation to the desired final direction
 	// is a post-process.
-	//  XX  Parameterize the size?
-	half := float32(1.5) // 4
+	half := 3*W/16 * float32(g.ArrowSize)  // 1.5, for the default geometry
 	x0 := x + 2*half   // tip of the arrowhead
 	y0 := y
 	x1 := x - half
@@ -44,13 +43,12 @@ func (t smallTriangle) Draw(out io.Writer) {
 		fallthrough
 	case svg.O_N:
 		// advance
-		x0 += 4
-		x1 += 4
-		x2 += 4
+		x0 += W/2
+		x1 += W/2
+		x2 += W/2
 	}
 
-	// <polygon> inherits both 'fill' and 'stroke' attributes from parents.
-	internal.MustFPrintf(out, svg.PolygonPrintFmt,
+	svg.WriteArrowhead(out, svg.SourceCell(t.Start),
 		x0, y0,
 		x1, y1,
 		x2, y2,
@@ -15,21 +15,27 @@ type Canvas struct {
 	// ? make explicitly a local member, requiring dot-qualification?
 	//     XX  would require wrapper accessor methods for .Width, .Height, RuneAt().
 	svg.CanvasCommon
+
+	// ReservedSet, as adjusted by svg.Config.RuneRoles.
+	reserved goat.RuneSet
 }
 
 func (ac *Canvas) GetCommon() *svg.CanvasCommon {
 	return &ac.CanvasCommon
 }
 
-// Copy-paste of ascii.NewCanvas() -- difference is in data types utf8.Canvas vs ascii.Canvas
-//    XX  simplify, by pushing call to svg.NewCanvasCommon() up to client?   
+// Makes the dialect available to svg.Render() and cmd/goat as "utf8".
+func init() {
+	svg.RegisterDialect("utf8", ReservedSet, NewCanvas)
+}
+
+// As ascii.NewCanvas(), the steps common to all dialects being taken by svg.ReadCanvas().
 func NewCanvas(config *svg.Config, in io.Reader) svg.AbstractCanvas {
-	c := Canvas{
-		CanvasCommon: svg.NewCanvasCommon(config, in),
+	c := &Canvas{
+		reserved: config.ReservedSet(ReservedSet),
 	}
-	// Fill the 'TextRunes' map, with runes removed from 'data', according to c.ShouldMoveToTextRunes()
-	svg.MoveToText(&c)
-	return &c
+	// User-defined joints are drawn as would be '┼'.
+	return svg.ReadCanvas(config, in, c, '┼')
 }
 
 // XX XX  Generalize to include '┆'
@@ -104,7 +110,7 @@ func (c *Canvas) ShouldMoveToTextRunes(i svg.XyIndex) bool {
 	i_r := c.RuneAt(i)
 	// character := string(i_r); _ = character   // for debug
 
-	if _, found := ReservedSet[i_r]; !found {
+	if _, found := c.reserved[i_r]; !found {
 		return true
 	}
 	return false